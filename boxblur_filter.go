@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+type BoxBlurFilter struct{}
+
+func (BoxBlurFilter) Name() string {
+	return "control.boxblur"
+}
+func (BoxBlurFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.boxblur.iterations", Kind: ParamInt, Min: 1, Max: 10, Default: 3},
+		linearLightParam,
+		pixelScaleParam,
+	}
+}
+func (f BoxBlurFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f BoxBlurFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	return boxBlur(ctx, rgbaPixels(img), p)
+}
+func (BoxBlurFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	return boxBlur(ctx, img.pixels(), f.Params)
+}
+
+func boxBlur[S sample](ctx context.Context, px pixels[S], p Params) error {
+	w, h := px.Width, px.Height
+	// each iteration reads from a copy of the last one so the bands being blurred at the same time
+	// never read pixels another band has already written
+	src := make([]S, len(px.Pix))
+	// the kernel is 3 preview pixels across, which is 3 times the pixel scale here
+	r := max(1, int(math.Round((3*PixelScale(p)-1)/2)))
+	area := float32((2*r + 1) * (2*r + 1))
+	// for each iteration
+	for range p.Int("control.boxblur.iterations") {
+		copy(src, px.Pix)
+		err := ParallelRowsContext(ctx, h, func(y0, y1 int) {
+			// for each pixel
+			for y := y0; y < y1; y++ {
+				for x := 0; x < w; x++ {
+					// if we're on an edge, skip
+					if x < r || y < r || x+r >= w || y+r >= h {
+						continue
+					}
+					// in the kernel get the mean of red green, blue and alpha
+					var sums [4]float32
+					for ky := -r; ky <= r; ky++ {
+						for kx := -r; kx <= r; kx++ {
+							i := (y+ky)*px.Stride + (x+kx)*4
+							sums[0] += float32(src[i+0])
+							sums[1] += float32(src[i+1])
+							sums[2] += float32(src[i+2])
+							sums[3] += float32(src[i+3])
+						}
+					}
+					// set the pixel to the mean of the surrounding pixels and itself. In 8 bits the mean has always
+					// been rounded down, that's kept so existing edits look the same
+					i := y*px.Stride + x*4
+					px.Pix[i+0] = S(sums[0] / area)
+					px.Pix[i+1] = S(sums[1] / area)
+					px.Pix[i+2] = S(sums[2] / area)
+					px.Pix[i+3] = S(sums[3] / area)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Contrast limited adaptive histogram equalization, which equalizes each tile of the image on its own and blends
+// between them. The clip limit stops flat areas like paper or sky from having their noise blown up
+type CLAHEFilter struct{}
+
+func (CLAHEFilter) Name() string {
+	return "control.clahe"
+}
+func (CLAHEFilter) Params() []FilterParam {
+	return []FilterParam{
+		equalizeChannelsParam("control.clahe.channels"),
+		{Key: "control.clahe.tilesize", Kind: ParamInt, Min: 8, Max: 512, Default: 64},
+		{Key: "control.clahe.cliplimit", Kind: ParamFloat, Min: 1, Max: 10, Default: 2},
+		linearLightParam,
+		pixelScaleParam,
+	}
+}
+func (CLAHEFilter) Apply(img *image.RGBA, p Params) {
+	tileSize := scalePixels(p, p.Int("control.clahe.tilesize"))
+	clipLimit := p.Float("control.clahe.cliplimit")
+	linear := LinearLight(p)
+	equalizePlanes(img, EqualizeChannels(p.Int("control.clahe.channels")), func(plane []uint8, width, height int) {
+		CLAHE(plane, width, height, tileSize, clipLimit, linear)
+	})
+}
+
+// ClipHistogram caps every count at limit and shares what was cut off out evenly between all the values
+func ClipHistogram(hist [256]int, limit int) [256]int {
+	excess := 0
+	for v, count := range hist {
+		if count > limit {
+			excess += count - limit
+			hist[v] = limit
+		}
+	}
+	for v := range hist {
+		hist[v] += excess / len(hist)
+	}
+	// spread what doesn't divide evenly across the whole range rather than all at the start
+	if remainder := excess % len(hist); remainder > 0 {
+		step := len(hist) / remainder
+		for v := 0; v < len(hist) && remainder > 0; v += step {
+			hist[v]++
+			remainder--
+		}
+	}
+	return hist
+}
+
+// CLAHE equalizes a width by height plane in place. It's split into tiles about tileSize across, each tile's
+// histogram is clipped at clipLimit times the mean count before it's equalized, and every value is blended between
+// the tables of the four tiles whose centres are nearest. When linear is set the blending is done in linear light,
+// the histograms are still of the values in the plane
+func CLAHE(plane []uint8, width, height, tileSize int, clipLimit float64, linear bool) {
+	if width == 0 || height == 0 {
+		return
+	}
+	// the tiles are spread evenly so there's no sliver left over at the edges
+	across, down := max(1, (width+tileSize/2)/tileSize), max(1, (height+tileSize/2)/tileSize)
+	luts := make([]LUT, across*down)
+	ParallelRows(down, func(ty0, ty1 int) {
+		for ty := ty0; ty < ty1; ty++ {
+			y0, y1 := ty*height/down, (ty+1)*height/down
+			for tx := range across {
+				x0, x1 := tx*width/across, (tx+1)*width/across
+				var hist [256]int
+				for y := y0; y < y1; y++ {
+					for _, v := range plane[y*width+x0 : y*width+x1] {
+						hist[v]++
+					}
+				}
+				limit := max(1, int(clipLimit*float64((x1-x0)*(y1-y0))/256))
+				luts[ty*across+tx] = EqualizeLUT(ClipHistogram(hist, limit), false)
+			}
+		}
+	})
+
+	// nearest tiles before and after position along a side split into n tiles of size, and how far between them it is
+	nearest := func(position, size float64, n int) (before, after int, t float32) {
+		g := position/size - 0.5
+		before = int(math.Floor(g))
+		t = float32(g - float64(before))
+		return Clamp(before, 0, n-1), Clamp(before+1, 0, n-1), t
+	}
+	read, write := channelScale(linear)
+	tileWidth, tileHeight := float64(width)/float64(across), float64(height)/float64(down)
+	ParallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			top, bottom, ty := nearest(float64(y)+0.5, tileHeight, down)
+			for x := range width {
+				left, right, tx := nearest(float64(x)+0.5, tileWidth, across)
+				i := y*width + x
+				v := plane[i]
+				upper := read(luts[top*across+left][v])*(1-tx) + read(luts[top*across+right][v])*tx
+				lower := read(luts[bottom*across+left][v])*(1-tx) + read(luts[bottom*across+right][v])*tx
+				plane[i] = write(upper*(1-ty) + lower*ty)
+			}
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCLAHEFilter(t *testing.T) {
+	t.Run("Clipping keeps the total", func(t *testing.T) {
+		// Aim: what's cut off the peaks is given back to the other values, nothing is lost
+		var hist [256]int
+		hist[10], hist[20] = 1000, 37
+		clipped := ClipHistogram(hist, 50)
+		total := 0
+		for _, count := range clipped {
+			total += count
+		}
+		if total != 1037 {
+			t.Errorf("Expected the total to stay 1037, got %d", total)
+		}
+		if clipped[10] > 50+1000/256+1 {
+			t.Errorf("Expected the peak to be clipped, got %d", clipped[10])
+		}
+	})
+	t.Run("A clip limit of 1 barely changes anything", func(t *testing.T) {
+		// Aim: clipping at the mean flattens every histogram, which is a straight line
+		img := randomImage(256, 256)
+		expected := ToRGBA(img)
+		p := NewFilters().Params
+		p["control.clahe.tilesize"] = 256
+		p["control.clahe.cliplimit"] = 1
+		CLAHEFilter{}.Apply(img, p)
+		for i := range img.Pix {
+			if diff := int(img.Pix[i]) - int(expected.Pix[i]); diff < -3 || diff > 3 {
+				t.Fatalf("Expected %d to stay about the same, got %d", expected.Pix[i], img.Pix[i])
+			}
+		}
+	})
+	t.Run("Each tile is stretched on its own", func(t *testing.T) {
+		// Aim: a dark tile next to a light one should still have its own detail stretched out
+		img := image.NewRGBA(image.Rect(0, 0, 128, 64))
+		for y := range 64 {
+			for x := range 128 {
+				// 20 to 40 on the left and 200 to 220 on the right
+				v := uint8(20 + (y*64+x%64)*21/4096)
+				if x >= 64 {
+					v += 180
+				}
+				i := y*img.Stride + x*4
+				img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 255
+			}
+		}
+		p := NewFilters().Params
+		p["control.clahe.tilesize"] = 64
+		p["control.clahe.cliplimit"] = 10
+		CLAHEFilter{}.Apply(img, p)
+		// the left quarter only uses the left tile's table
+		lowest, highest := uint8(255), uint8(0)
+		for y := range 64 {
+			for x := range 32 {
+				v := img.Pix[y*img.Stride+x*4]
+				lowest, highest = min(lowest, v), max(highest, v)
+			}
+		}
+		if int(highest)-int(lowest) < 200 {
+			t.Errorf("Expected the dark tile to be stretched, got %d to %d", lowest, highest)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// The command line mode runs the filter pipeline without ever opening a window, so it works without a display
+// e.g. nea process -i in.png -o out.tiff --grayscale --dithering.buckets 8 --order grayscale,dithering
+// or to export a saved project: nea process -p edit.nea -o out.png
+
+// RunCommand runs the subcommand in args and returns the exit code for the process
+func RunCommand(args []string) int {
+	var err error
+	switch args[0] {
+	case "process":
+		err = ProcessCommand(args[1:])
+	default:
+		err = fmt.Errorf("unknown command %q, expected \"process\"", args[0])
+	}
+	if err != nil {
+		ErrorLogf("%v", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// ProcessOptions holds everything the process command needs to run
+type ProcessOptions struct {
+	InputPath  string
+	OutputPath string
+	Format     FileFormat
+	Filters    Filters
+	// Set when the image and filters come from a project file
+	Project *Project
+}
+
+// flagName is the name a filter or parameter key is given on the command line
+func flagName(key string) string {
+	return strings.TrimPrefix(key, "control.")
+}
+
+// ParseProcessArgs turns the process command's arguments into options, every filter and parameter in
+// FilterRegistry gets a flag so new filters don't need any changes here
+func ParseProcessArgs(args []string) (ProcessOptions, error) {
+	opts := ProcessOptions{Filters: NewFilters()}
+	// only the filters asked for on the command line are run
+	for k := range opts.Filters.Enabled {
+		opts.Filters.Enabled[k] = false
+	}
+
+	fs := flag.NewFlagSet("process", flag.ContinueOnError)
+	fs.StringVar(&opts.InputPath, "i", "", "input image path")
+	projectPath := fs.String("p", "", "project file to take the image and filters from, other flags override it")
+	fs.StringVar(&opts.OutputPath, "o", "", "output image path, the format is taken from the extension")
+	order := fs.String("order", "", "comma separated order to apply the filters in, unlisted filters run afterwards")
+	linear := fs.Bool("linear", false, "blend in linear light in the filters that support it")
+
+	// map each flag back to the filter or parameter it came from
+	enableFlags := map[string]*bool{}
+	paramFlags := map[string]*float64{}
+	choiceFlags := map[string]*string{}
+	paramOwner := map[string]string{}
+	for _, filter := range FilterRegistry {
+		enableFlags[flagName(filter.Name())] = fs.Bool(flagName(filter.Name()), false, "enable the "+flagName(filter.Name())+" filter")
+		for _, p := range filter.Params() {
+			if p.Hidden {
+				continue
+			}
+			if p.Kind == ParamChoice {
+				// choices are given by name rather than by index
+				names := choiceNames(p)
+				choiceFlags[flagName(p.Key)] = fs.String(flagName(p.Key), names[int(p.Default)], fmt.Sprintf("one of %v, setting this enables %v", strings.Join(names, ", "), flagName(filter.Name())))
+			} else {
+				paramFlags[flagName(p.Key)] = fs.Float64(flagName(p.Key), p.Default, fmt.Sprintf("%v to %v, setting this enables %v", p.Min, p.Max, flagName(filter.Name())))
+			}
+			paramOwner[flagName(p.Key)] = filter.Name()
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if *projectPath != "" {
+		project, err := LoadProjectFile(*projectPath)
+		if err != nil {
+			return opts, fmt.Errorf("couldn't load project %v: %w", *projectPath, err)
+		}
+		opts.Project = &project
+		opts.Filters = project.Filters
+	}
+	if (opts.InputPath == "" && opts.Project == nil) || opts.OutputPath == "" {
+		return opts, errors.New("-o and one of -i or -p must be given")
+	}
+	format, ok := FileFormatFromExtension(strings.TrimPrefix(filepath.Ext(opts.OutputPath), "."))
+	if !ok {
+		return opts, fmt.Errorf("unsupported output extension %q", filepath.Ext(opts.OutputPath))
+	}
+	opts.Format = format
+
+	// only look at the flags that were actually set so defaults don't enable anything, every bad flag is reported
+	// and none of them are used
+	var errs []error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "linear" {
+			opts.Filters.Params[LinearLightKey] = 0
+			if *linear {
+				opts.Filters.Params[LinearLightKey] = 1
+			}
+		}
+		if enabled, ok := enableFlags[f.Name]; ok {
+			opts.Filters.Enabled["control."+f.Name] = *enabled
+		}
+		if value, ok := paramFlags[f.Name]; ok {
+			filter, _ := GetFilter(paramOwner[f.Name])
+			for _, p := range filter.Params() {
+				if flagName(p.Key) != f.Name {
+					continue
+				}
+				if *value < p.Min || *value > p.Max {
+					errs = append(errs, fmt.Errorf("--%v must be between %v and %v", f.Name, p.Min, p.Max))
+					continue
+				}
+				opts.Filters.Params[p.Key] = *value
+				opts.Filters.Enabled[paramOwner[f.Name]] = true
+			}
+		}
+		if value, ok := choiceFlags[f.Name]; ok {
+			filter, _ := GetFilter(paramOwner[f.Name])
+			for _, p := range filter.Params() {
+				if flagName(p.Key) != f.Name {
+					continue
+				}
+				names := choiceNames(p)
+				i := slices.Index(names, *value)
+				if i < 0 {
+					errs = append(errs, fmt.Errorf("--%v must be one of %v", f.Name, strings.Join(names, ", ")))
+					continue
+				}
+				opts.Filters.Params[p.Key] = float64(i)
+				opts.Filters.Enabled[paramOwner[f.Name]] = true
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return opts, err
+	}
+
+	var err error
+	if *order != "" {
+		opts.Filters.Order, err = parseOrder(*order, opts.Filters.Order)
+	}
+	return opts, err
+}
+
+// choiceNames are the command line names of a ParamChoice parameter's options, the last part of each translation key
+func choiceNames(p FilterParam) []string {
+	names := make([]string, len(p.Options))
+	for i, option := range p.Options {
+		names[i] = option[strings.LastIndex(option, ".")+1:]
+	}
+	return names
+}
+
+// parseOrder puts the comma separated filters in list first, followed by the rest of defaultOrder
+func parseOrder(list string, defaultOrder []string) ([]string, error) {
+	res := make([]string, 0, len(defaultOrder))
+	for _, name := range strings.Split(list, ",") {
+		key := "control." + strings.TrimSpace(name)
+		if _, ok := GetFilter(key); !ok {
+			return nil, fmt.Errorf("unknown filter %q in --order", name)
+		}
+		res = append(res, key)
+	}
+	res = removeDuplicates(res)
+	for _, key := range defaultOrder {
+		if !slices.Contains(res, key) {
+			res = append(res, key)
+		}
+	}
+	return res, nil
+}
+
+// ProcessCommand loads an image, runs it through the filter pipeline and saves the result
+func ProcessCommand(args []string) error {
+	opts, err := ParseProcessArgs(args)
+	if err != nil {
+		return err
+	}
+	var img image.Image
+	if opts.InputPath != "" {
+		InfoLogf("Processing %v", opts.InputPath)
+		img, err = DecodeImageFile(opts.InputPath)
+	} else {
+		InfoLog("Processing the project's image")
+		img, err = opts.Project.LoadImage()
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't load image: %w", err)
+	}
+	rgba := ToRGBA(img)
+	opts.Filters.Apply(rgba)
+
+	f, err := os.Create(opts.OutputPath)
+	if err != nil {
+		return fmt.Errorf("couldn't create %v: %w", opts.OutputPath, err)
+	}
+	defer f.Close()
+	if err = EncodeImage(f, rgba, opts.Format); err != nil {
+		return fmt.Errorf("couldn't encode %v: %w", opts.OutputPath, err)
+	}
+	InfoLogf("Saved %v", opts.OutputPath)
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseProcessArgs(t *testing.T) {
+	t.Run("Filters and order", func(t *testing.T) {
+		opts, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.tiff", "--grayscale", "--dithering.buckets", "8", "--order", "dithering,grayscale"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.Format != TIFF {
+			t.Errorf("Expected format %v, got %v", TIFF, opts.Format)
+		}
+		if !opts.Filters.Enabled["control.grayscale"] || !opts.Filters.Enabled["control.dithering"] {
+			t.Errorf("Expected grayscale and dithering enabled, got %v", opts.Filters.Enabled)
+		}
+		if opts.Filters.Enabled["control.boxblur"] {
+			t.Error("Box blur was enabled without being asked for")
+		}
+		if opts.Filters.Params.Int("control.dithering.buckets") != 8 {
+			t.Errorf("Expected 8 buckets, got %v", opts.Filters.Params["control.dithering.buckets"])
+		}
+		if !slices.Equal(opts.Filters.Order[:2], []string{"control.dithering", "control.grayscale"}) || len(opts.Filters.Order) != len(FilterRegistry) {
+			t.Errorf("Unexpected order %v", opts.Filters.Order)
+		}
+	})
+	t.Run("Crop and resize in pixels", func(t *testing.T) {
+		// Aim: the pixel modes are chosen by name and their sizes set like any other parameter
+		opts, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--crop.mode", "pixels", "--crop.x", "10", "--crop.width", "300", "--resize.mode", "size", "--resize.targetwidth", "800", "--resize.targetheight", "600"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := opts.Filters.Params
+		if CropMode(p.Int("control.crop.mode")) != CropPixels || p.Int("control.crop.x") != 10 || p.Int("control.crop.width") != 300 {
+			t.Errorf("Unexpected crop %v %v %v", p["control.crop.mode"], p["control.crop.x"], p["control.crop.width"])
+		}
+		if w, h := ResizedSize(1000, 1000, p); w != 800 || h != 600 {
+			t.Errorf("Expected to resize to 800x600, got %dx%d", w, h)
+		}
+		if !opts.Filters.Enabled["control.crop"] || !opts.Filters.Enabled["control.resize"] {
+			t.Error("Expected crop and resize to be enabled")
+		}
+	})
+	t.Run("Unknown filter in order", func(t *testing.T) {
+		_, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--order", "grayscale,sepia"})
+		if err == nil {
+			t.Error("Expected an error for an unknown filter")
+		}
+	})
+	t.Run("Unknown output format", func(t *testing.T) {
+		_, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.gif"})
+		if err == nil {
+			t.Error("Expected an error for an unsupported extension")
+		}
+	})
+	t.Run("Parameter out of range", func(t *testing.T) {
+		_, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--brightness", "5"})
+		if err == nil {
+			t.Error("Expected an error for an out of range parameter")
+		}
+	})
+	t.Run("Every bad flag is reported", func(t *testing.T) {
+		// Aim: all the invalid flags should be in the error, not just the last one, and none of them are used
+		opts, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--brightness", "5", "--gaussianblur.edgemode", "smear"})
+		if err == nil {
+			t.Fatal("Expected an error for the bad flags")
+		}
+		for _, name := range []string{"--brightness", "--gaussianblur.edgemode"} {
+			if !strings.Contains(err.Error(), name) {
+				t.Errorf("Expected %v in the error, got %v", name, err)
+			}
+		}
+		if opts.Filters.Params["control.gaussianblur.edgemode"] < 0 {
+			t.Error("Expected the bad choice not to be stored")
+		}
+	})
+	t.Run("Choice parameter", func(t *testing.T) {
+		// Aim: choices are given by name and unknown names are rejected
+		opts, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--gaussianblur.edgemode", "wrap"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if EdgeMode(opts.Filters.Params.Int("control.gaussianblur.edgemode")) != EdgeWrap || !opts.Filters.Enabled["control.gaussianblur"] {
+			t.Errorf("Expected gaussian blur enabled with wrapped edges, got %v", opts.Filters.Params["control.gaussianblur.edgemode"])
+		}
+		if _, err = ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--gaussianblur.edgemode", "smear"}); err == nil {
+			t.Error("Expected an error for an unknown choice")
+		}
+	})
+	t.Run("Linear light", func(t *testing.T) {
+		// Aim: linear light isn't any one filter's parameter so it has its own flag
+		opts, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--linear", "--boxblur"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !LinearLight(opts.Filters.Params) {
+			t.Error("Expected linear light to be on")
+		}
+	})
+}
+
+func TestProcessCommand(t *testing.T) {
+	// Aim: the command line should load, filter and save an image without a window
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.png")
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+	img.SetRGBA(1, 0, color.RGBA{G: 99, A: 255})
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err = ProcessCommand([]string{"-i", in, "-o", out, "--grayscale"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := DecodeImageFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, _ := res.At(0, 0).RGBA()
+	if r>>8 != 85 || g>>8 != 85 || b>>8 != 85 {
+		t.Error("Expected 85, 85, 85, got", r>>8, g>>8, b>>8)
+	}
+}
@@ -1,5 +1,23 @@
 package main
 
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigVersion is bumped whenever the settings file changes in a way older versions can't read
+const ConfigVersion = 1
+
+// Font sizes the settings window allows
+const (
+	MinFontSize = 10
+	MaxFontSize = 42
+)
+
 type Language int32
 
 
@@ -16,6 +34,21 @@ func (f FileFormat) String() string {
 	return [...]string{"png", "jpg", "tiff", "bmp"}[int32(f)]
 }
 
+// FileFormatFromExtension matches a file extension like "png" or "jpeg" to its format
+func FileFormatFromExtension(extension string) (FileFormat, bool) {
+	switch strings.ToLower(extension) {
+	case "png":
+		return PNG, true
+	case "jpg", "jpeg":
+		return JPG, true
+	case "tiff":
+		return TIFF, true
+	case "bmp":
+		return BMP, true
+	}
+	return PNG, false
+}
+
 type Theme int32
 
 const (
@@ -36,6 +69,7 @@ const (
 )
 
 type Config struct {
+	Version           int
 	Language          Language
 	FileFormat        FileFormat
 	ActiveFormatIndex int32
@@ -48,6 +82,80 @@ func (c *Config) GetActiveFileFormat() FileFormat {
 	return FileFormat(c.ActiveFormatIndex)
 }
 
+// NewConfig has the default settings, used for anything missing from the settings file
 func NewConfig() Config {
-	return Config{Language: English, FileFormat: TIFF, CurrentTheme: ThemeLight, CurrentFont: FontZapfino, FontSize: 18}
+	return Config{Version: ConfigVersion, Language: English, FileFormat: TIFF, ActiveFormatIndex: int32(TIFF), CurrentTheme: ThemeLight, CurrentFont: FontDefault, FontSize: MinFontSize}
+}
+
+// ConfigPath is where the settings are saved, in $XDG_CONFIG_HOME if it's set on any platform, otherwise the
+// platform's usual config directory
+func ConfigPath() (string, error) {
+	// os.UserConfigDir only looks at XDG_CONFIG_HOME on Linux and the BSDs, relative paths are ignored as the
+	// XDG spec says they should be
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(dir) {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "nea", "config.json"), nil
+}
+
+// LoadConfig reads the settings file at path, anything missing or invalid is left at its default.
+// A missing file isn't an error, it just means the defaults are used
+func LoadConfig(path string) (Config, error) {
+	c := NewConfig()
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	// decoding over the defaults means missing keys keep their default value
+	if err = json.Unmarshal(content, &c); err != nil {
+		return NewConfig(), err
+	}
+	if c.Version > ConfigVersion {
+		ErrorLogf("Settings version %d is newer than %d, some settings may be ignored", c.Version, ConfigVersion)
+	}
+	c.Version = ConfigVersion
+	return c.validated(), nil
+}
+
+// validated puts anything out of range back to its default so a hand edited file can't crash the app
+func (c Config) validated() Config {
+	defaults := NewConfig()
+	if c.Language < 0 || c.Language >= LanguageCount {
+		c.Language = defaults.Language
+	}
+	if c.FileFormat < PNG || c.FileFormat > BMP {
+		c.FileFormat = defaults.FileFormat
+	}
+	if c.ActiveFormatIndex < int32(PNG) || c.ActiveFormatIndex > int32(BMP) {
+		c.ActiveFormatIndex = defaults.ActiveFormatIndex
+	}
+	if c.CurrentTheme != ThemeLight && c.CurrentTheme != ThemeDark {
+		c.CurrentTheme = defaults.CurrentTheme
+	}
+	if c.CurrentFont < 0 || c.CurrentFont >= FontCount {
+		c.CurrentFont = defaults.CurrentFont
+	}
+	if c.FontSize < MinFontSize || c.FontSize > MaxFontSize {
+		c.FontSize = defaults.FontSize
+	}
+	return c
+}
+
+// SaveConfig writes the settings to path, creating the folder if it needs to
+func SaveConfig(path string, c Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigPath(t *testing.T) {
+	t.Run("XDG config directory", func(t *testing.T) {
+		// Aim: settings should live in the XDG config directory when it's set, whatever the platform
+		dir := filepath.Join(t.TempDir(), "xdg")
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		path, err := ConfigPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := filepath.Join(dir, "nea", "config.json"); path != expected {
+			t.Errorf("Expected %v, got %v", expected, path)
+		}
+	})
+	t.Run("Relative XDG config directory", func(t *testing.T) {
+		// Aim: a relative XDG_CONFIG_HOME is invalid so the platform's config directory is used instead
+		t.Setenv("XDG_CONFIG_HOME", "xdg")
+		path, err := ConfigPath()
+		if err != nil {
+			t.Skip("No config directory on this platform:", err)
+		}
+		if !filepath.IsAbs(path) {
+			t.Errorf("Expected an absolute path, got %v", path)
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Missing file", func(t *testing.T) {
+		c, err := LoadConfig(filepath.Join(t.TempDir(), "config.json"))
+		if err != nil || c != NewConfig() {
+			t.Errorf("Expected the defaults without an error, got %+v %v", c, err)
+		}
+	})
+	t.Run("Round trip", func(t *testing.T) {
+		// Aim: saved settings should come back exactly, including creating the folder
+		path := filepath.Join(t.TempDir(), "nea", "config.json")
+		c := NewConfig()
+		c.Language = German
+		c.CurrentTheme = ThemeDark
+		c.FontSize = 20
+		if err := SaveConfig(path, c); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadConfig(path)
+		if err != nil || loaded != c {
+			t.Errorf("Expected %+v, got %+v %v", c, loaded, err)
+		}
+	})
+	t.Run("Missing keys", func(t *testing.T) {
+		// Aim: keys missing from the file should get their default values
+		path := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(path, []byte(`{"Version": 1, "CurrentTheme": 1}`), 0o644)
+		c, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.CurrentTheme != ThemeDark || c.FontSize != NewConfig().FontSize || c.FileFormat != NewConfig().FileFormat {
+			t.Errorf("Unexpected config %+v", c)
+		}
+	})
+	t.Run("Invalid values", func(t *testing.T) {
+		// Aim: out of range values shouldn't be used as indexes later
+		path := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(path, []byte(`{"Language": 7, "CurrentFont": -1, "FontSize": 900, "ActiveFormatIndex": 12}`), 0o644)
+		c, err := LoadConfig(path)
+		if err != nil || c != NewConfig() {
+			t.Errorf("Expected the defaults, got %+v %v", c, err)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+)
+
+// MaxKernelSize is the widest kernel the convolution filter can use, smaller kernels use the middle of the grid
+const MaxKernelSize = 7
+
+// KernelCombine decides what happens to the result of each kernel
+type KernelCombine int
+
+const (
+	CombineSingle    KernelCombine = iota // only the first kernel is used
+	CombineMagnitude                      // the length of both kernels' results, e.g. Sobel x and y
+)
+
+// KernelKey is the parameter holding one cell of a kernel, pair is false for the first kernel and true for the
+// second. Rows and columns go from 0 to MaxKernelSize-1 with the middle of the kernel at MaxKernelSize/2
+func KernelKey(pair bool, row, col int) string {
+	if pair {
+		return fmt.Sprintf("control.convolution.pair.%d.%d", row, col)
+	}
+	return fmt.Sprintf("control.convolution.kernel.%d.%d", row, col)
+}
+
+// KernelSize is the width of the kernel chosen in p
+func KernelSize(p Params) int {
+	return 3 + 2*p.Int("control.convolution.size")
+}
+
+type ConvolutionFilter struct{}
+
+func (ConvolutionFilter) Name() string {
+	return "control.convolution"
+}
+func (ConvolutionFilter) Params() []FilterParam {
+	params := []FilterParam{
+		{Key: "control.convolution.size", Kind: ParamChoice, Min: 0, Max: 2, Default: 0, Options: []string{"control.convolution.size.3", "control.convolution.size.5", "control.convolution.size.7"}},
+		{Key: "control.convolution.divisor", Kind: ParamFloat, Min: 0.01, Max: 256, Default: 1},
+		{Key: "control.convolution.bias", Kind: ParamFloat, Min: -255, Max: 255, Default: 0},
+		{Key: "control.convolution.combine", Kind: ParamChoice, Min: 0, Max: 1, Default: float64(CombineSingle), Options: []string{"control.convolution.single", "control.convolution.magnitude"}},
+		{Key: "control.convolution.edgemode", Kind: ParamChoice, Min: 0, Max: float64(len(EdgeModeOptions) - 1), Default: float64(EdgeClamp), Options: EdgeModeOptions},
+		linearLightParam,
+		pixelScaleParam,
+	}
+	// the kernels themselves are typed into the convolution window, they default to leaving the image as it is
+	for _, pair := range []bool{false, true} {
+		for row := range MaxKernelSize {
+			for col := range MaxKernelSize {
+				var value float64
+				if !pair && row == MaxKernelSize/2 && col == MaxKernelSize/2 {
+					value = 1
+				}
+				params = append(params, FilterParam{Key: KernelKey(pair, row, col), Kind: ParamFloat, Min: -1000, Max: 1000, Default: value, Hidden: true})
+			}
+		}
+	}
+	return params
+}
+
+// kernel reads the size x size kernel from p as a flat slice
+func kernel(p Params, pair bool, size int) []float64 {
+	offset := (MaxKernelSize - size) / 2
+	res := make([]float64, 0, size*size)
+	for row := range size {
+		for col := range size {
+			res = append(res, p.Float(KernelKey(pair, row+offset, col+offset)))
+		}
+	}
+	return res
+}
+
+func (f ConvolutionFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f ConvolutionFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	return convolve(ctx, rgbaPixels(img), p)
+}
+func (ConvolutionFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	return convolve(ctx, img.pixels(), f.Params)
+}
+
+func convolve[S sample](ctx context.Context, px pixels[S], p Params) error {
+	w, h := px.Width, px.Height
+	size := KernelSize(p)
+	first := kernel(p, false, size)
+	var second []float64
+	if KernelCombine(p.Int("control.convolution.combine")) == CombineMagnitude {
+		second = kernel(p, true, size)
+	}
+	divisor := p.Float("control.convolution.divisor")
+	bias := p.Float("control.convolution.bias")
+	if px.Linear {
+		// the bias is a level in sRGB, so flat areas of an emboss still come out mid grey
+		bias = math.Copysign(DecodeSRGB(min(math.Abs(bias), 255)/255)*255, bias)
+	}
+	mode := EdgeMode(p.Int("control.convolution.edgemode"))
+
+	// read from a copy so the bands never see pixels another band has already written
+	src := px.clone().Pix
+	// at a bigger pixel scale each preview pixel is a block of spacing pixels, so the kernel reads the average of
+	// each block and its cells are a block apart
+	spacing := scalePixels(p, 1)
+	if spacing > 1 {
+		average := px.clone()
+		box := slices.Repeat([]float32{1 / float32(spacing/2*2+1)}, spacing/2*2+1)
+		if err := convolveSeparable(ctx, average, box, box, mode); err != nil {
+			return err
+		}
+		src = average.Pix
+	}
+	r := size / 2
+	return ParallelRowsContext(ctx, h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sums, pairSums [3]float64
+				for ky := range size {
+					row := edgeIndex(y+(ky-r)*spacing, h, mode) * px.Stride
+					for kx := range size {
+						i := row + edgeIndex(x+(kx-r)*spacing, w, mode)*4
+						k := ky*size + kx
+						for c := range 3 {
+							sums[c] += first[k] * float64(src[i+c])
+							if second != nil {
+								pairSums[c] += second[k] * float64(src[i+c])
+							}
+						}
+					}
+				}
+				// alpha is left as it is so edge maps stay visible
+				i := y*px.Stride + x*4
+				for c := range 3 {
+					v := sums[c]
+					if second != nil {
+						v = math.Hypot(sums[c], pairSums[c])
+					}
+					px.Pix[i+c] = px.write(float32(v/divisor + bias))
+				}
+			}
+		}
+	})
+}
+
+// KernelPreset is a ready made kernel that can be loaded into the convolution filter
+type KernelPreset struct {
+	Name    string // translation key
+	Kernel  [][]float64
+	Pair    [][]float64 // only set for presets that take the magnitude of two kernels
+	Divisor float64
+	Bias    float64
+}
+
+var KernelPresets = []KernelPreset{
+	{
+		Name:    "window.convolution.preset.identity",
+		Kernel:  [][]float64{{0, 0, 0}, {0, 1, 0}, {0, 0, 0}},
+		Divisor: 1,
+	},
+	{
+		Name:    "window.convolution.preset.sobel",
+		Kernel:  [][]float64{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}},
+		Pair:    [][]float64{{-1, -2, -1}, {0, 0, 0}, {1, 2, 1}},
+		Divisor: 1,
+	},
+	{
+		Name:    "window.convolution.preset.prewitt",
+		Kernel:  [][]float64{{-1, 0, 1}, {-1, 0, 1}, {-1, 0, 1}},
+		Pair:    [][]float64{{-1, -1, -1}, {0, 0, 0}, {1, 1, 1}},
+		Divisor: 1,
+	},
+	{
+		Name:    "window.convolution.preset.laplacian",
+		Kernel:  [][]float64{{0, -1, 0}, {-1, 4, -1}, {0, -1, 0}},
+		Divisor: 1,
+	},
+	{
+		Name:    "window.convolution.preset.emboss",
+		Kernel:  [][]float64{{-1, -1, 0}, {-1, 0, 1}, {0, 1, 1}},
+		Divisor: 1,
+		Bias:    128,
+	},
+	{
+		Name:    "window.convolution.preset.outline",
+		Kernel:  [][]float64{{-1, -1, -1}, {-1, 8, -1}, {-1, -1, -1}},
+		Divisor: 1,
+	},
+}
+
+// Apply the preset to the convolution filter's parameters in f and enable it
+func (k KernelPreset) Apply(f Filters) {
+	size := len(k.Kernel)
+	offset := (MaxKernelSize - size) / 2
+	for _, pair := range []bool{false, true} {
+		cells := k.Kernel
+		if pair {
+			cells = k.Pair
+		}
+		for row := range MaxKernelSize {
+			for col := range MaxKernelSize {
+				var value float64
+				r, c := row-offset, col-offset
+				if r >= 0 && r < len(cells) && c >= 0 && c < len(cells) {
+					value = cells[r][c]
+				}
+				f.Params[KernelKey(pair, row, col)] = value
+			}
+		}
+	}
+	combine := CombineSingle
+	if k.Pair != nil {
+		combine = CombineMagnitude
+	}
+	f.Params["control.convolution.size"] = float64((size - 3) / 2)
+	f.Params["control.convolution.combine"] = float64(combine)
+	f.Params["control.convolution.divisor"] = k.Divisor
+	f.Params["control.convolution.bias"] = k.Bias
+	f.Enabled[ConvolutionFilter{}.Name()] = true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestConvolutionFilter(t *testing.T) {
+	// a vertical edge from black to white half way across
+	step := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 10, 6))
+		for y := range 6 {
+			for x := range 10 {
+				v := uint8(0)
+				if x >= 5 {
+					v = 255
+				}
+				img.Set(x, y, color.RGBA{v, v, v, 255})
+			}
+		}
+		return img
+	}
+	preset := func(name string) Filters {
+		f := NewFilters()
+		i := slices.IndexFunc(KernelPresets, func(k KernelPreset) bool { return k.Name == name })
+		KernelPresets[i].Apply(f)
+		return f
+	}
+
+	t.Run("Default kernel leaves the image alone", func(t *testing.T) {
+		img := randomImage(16, 16)
+		expected := ToRGBA(img)
+		ConvolutionFilter{}.Apply(img, NewFilters().Params)
+		if !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Identity kernel changed the image")
+		}
+	})
+	t.Run("Sobel finds edges", func(t *testing.T) {
+		// Aim: the magnitude of the paired kernels should be bright at the edge and black in flat areas
+		f := preset("window.convolution.preset.sobel")
+		if !f.Enabled["control.convolution"] || KernelCombine(f.Params.Int("control.convolution.combine")) != CombineMagnitude {
+			t.Fatal("Sobel preset didn't enable the filter with paired kernels")
+		}
+		for _, mode := range []EdgeMode{EdgeClamp, EdgeMirror} {
+			f.Params["control.convolution.edgemode"] = float64(mode)
+			img := step()
+			ConvolutionFilter{}.Apply(img, f.Params)
+			if edge := img.RGBAAt(5, 3).R; edge != 255 {
+				t.Errorf("Edge mode %d: expected the edge to be white, got %d", mode, edge)
+			}
+			if flat := img.RGBAAt(1, 0).R; flat != 0 {
+				t.Errorf("Edge mode %d: expected a flat area to be black, got %d", mode, flat)
+			}
+		}
+	})
+	t.Run("Wrapping finds the image edge", func(t *testing.T) {
+		// Aim: wrapping puts white next to black at the left edge so that's an edge too
+		f := preset("window.convolution.preset.sobel")
+		f.Params["control.convolution.edgemode"] = float64(EdgeWrap)
+		img := step()
+		ConvolutionFilter{}.Apply(img, f.Params)
+		if edge := img.RGBAAt(0, 3).R; edge != 255 {
+			t.Errorf("Expected the wrapped edge to be white, got %d", edge)
+		}
+	})
+	t.Run("Bias", func(t *testing.T) {
+		// Aim: emboss on a flat image is just the bias
+		f := preset("window.convolution.preset.emboss")
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		ConvolutionFilter{}.Apply(img, f.Params)
+		if v := img.RGBAAt(2, 2).R; v != 128 {
+			t.Errorf("Expected 128, got %d", v)
+		}
+	})
+	t.Run("Larger kernels", func(t *testing.T) {
+		// Aim: a 5x5 mean with a divisor of 25 keeps a flat image the same
+		f := NewFilters()
+		f.Params["control.convolution.size"] = 1
+		f.Params["control.convolution.divisor"] = 25
+		for row := 1; row < 6; row++ {
+			for col := 1; col < 6; col++ {
+				f.Params[KernelKey(false, row, col)] = 1
+			}
+		}
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		for i := range img.Pix {
+			img.Pix[i] = 90
+		}
+		ConvolutionFilter{}.Apply(img, f.Params)
+		if v := img.RGBAAt(0, 0).R; v != 90 {
+			t.Errorf("Expected 90, got %d", v)
+		}
+	})
+	t.Run("Kernel cells aren't flags or history labels", func(t *testing.T) {
+		// Aim: the kernel is edited in its own window so it shouldn't show up as separate parameters
+		prev := NewFilters()
+		next := prev.Clone()
+		next.Params[KernelKey(false, 3, 4)] = 2
+		if key := changedKey(prev, next); key != "control.convolution" {
+			t.Errorf("Expected the filter's name in the history, got %v", key)
+		}
+		if _, err := ParseProcessArgs([]string{"-i", "in.png", "-o", "out.png", "--convolution.kernel.3.3", "2"}); err == nil {
+			t.Error("Expected no flag for a kernel cell")
+		}
+	})
+}
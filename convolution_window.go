@@ -0,0 +1,123 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gui "github.com/gen2brain/raylib-go/raygui"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ConvolutionWindow is where the convolution filter's kernels are typed in
+type ConvolutionWindow struct {
+	Showing        bool
+	Anchor         rl.Vector2
+	InteractedWith time.Time
+	// Parameter key of the number box being typed in, "" if there isn't one
+	Editing  string
+	EditText string
+	// Result of the last edit, shown at the bottom of the window
+	Status string
+}
+
+func (c *ConvolutionWindow) getRect() rl.Rectangle {
+	return rl.NewRectangle(c.Anchor.X, c.Anchor.Y, 480, 420)
+}
+
+// numberBox is a text box for a convolution parameter, the parameter is only changed once editing finishes
+func (c *ConvolutionWindow) numberBox(rect rl.Rectangle, key string) {
+	text := strconv.FormatFloat(state.Filters.Params[key], 'g', -1, 64)
+	editing := c.Editing == key
+	if editing {
+		text = c.EditText
+	}
+	if gui.TextBox(rect, &text, 16, editing) {
+		if editing {
+			c.finishEditing()
+		} else {
+			// clicking straight from one box to another should still keep what was typed in the first
+			c.finishEditing()
+			c.Editing = key
+		}
+	}
+	if c.Editing == key {
+		c.EditText = text
+	}
+}
+
+// finishEditing sets the parameter being edited to whatever was typed, as long as it's a number
+func (c *ConvolutionWindow) finishEditing() {
+	if c.Editing == "" {
+		return
+	}
+	key := c.Editing
+	c.Editing = ""
+	value, err := strconv.ParseFloat(strings.TrimSpace(c.EditText), 64)
+	if err != nil {
+		c.Status = Translate("window.convolution.invalid")
+		return
+	}
+	params := ConvolutionFilter{}.Params()
+	i := slices.IndexFunc(params, func(p FilterParam) bool { return p.Key == key })
+	state.Filters.Params[key] = min(max(value, params[i].Min), params[i].Max)
+	state.Filters.Enabled[ConvolutionFilter{}.Name()] = true
+	state.History.Record(state.Filters)
+	c.Status = ""
+}
+
+// choice draws a toggle group for a ParamChoice parameter
+func (c *ConvolutionWindow) choice(rect rl.Rectangle, key string) {
+	params := ConvolutionFilter{}.Params()
+	p := params[slices.IndexFunc(params, func(p FilterParam) bool { return p.Key == key })]
+	options := MapOut(p.Options, Translate)
+	active := gui.ToggleGroup(rect, strings.Join(options, ";"), int32(state.Filters.Params.Int(key)))
+	state.Filters.Params[key] = float64(active)
+}
+
+// Draw the convolution window
+func (c *ConvolutionWindow) Draw() {
+	c.Showing = !gui.WindowBox(c.getRect(), Translate("window.convolution.title"))
+	if !c.Showing {
+		// otherwise the hotkeys would stay blocked by a box nobody can see
+		c.finishEditing()
+	}
+	name := ConvolutionFilter{}.Name()
+	state.Filters.Enabled[name] = gui.CheckBox(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+35, 10, 10), Translate(name), state.Filters.Enabled[name])
+	// Preset buttons
+	for i, preset := range KernelPresets {
+		if gui.Button(rl.NewRectangle(c.Anchor.X+10+float32(i)*77, c.Anchor.Y+55, 72, 25), Translate(preset.Name)) {
+			preset.Apply(state.Filters)
+			state.History.Record(state.Filters)
+		}
+	}
+	// Size, how to combine the kernels and what to do at the edges
+	c.choice(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+90, 50, 20), "control.convolution.size")
+	c.choice(rl.NewRectangle(c.Anchor.X+240, c.Anchor.Y+90, 110, 20), "control.convolution.combine")
+	c.choice(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+115, 70, 20), "control.convolution.edgemode")
+
+	// Kernel grids, the second one is only used when taking the magnitude
+	size := KernelSize(state.Filters.Params)
+	offset := (MaxKernelSize - size) / 2
+	pairs := []bool{false}
+	if KernelCombine(state.Filters.Params.Int("control.convolution.combine")) == CombineMagnitude {
+		pairs = append(pairs, true)
+	}
+	for i, pair := range pairs {
+		x := c.Anchor.X + 10 + float32(i)*230
+		for row := range size {
+			for col := range size {
+				rect := rl.NewRectangle(x+float32(col)*32, c.Anchor.Y+145+float32(row)*27, 30, 25)
+				c.numberBox(rect, KernelKey(pair, row+offset, col+offset))
+			}
+		}
+	}
+
+	// Divisor and bias
+	gui.Label(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+340, 100, 25), Translate("control.convolution.divisor"))
+	c.numberBox(rl.NewRectangle(c.Anchor.X+110, c.Anchor.Y+340, 100, 25), "control.convolution.divisor")
+	gui.Label(rl.NewRectangle(c.Anchor.X+240, c.Anchor.Y+340, 100, 25), Translate("control.convolution.bias"))
+	c.numberBox(rl.NewRectangle(c.Anchor.X+340, c.Anchor.Y+340, 100, 25), "control.convolution.bias")
+	gui.Label(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+380, 460, 20), c.Status)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// EdgeMode decides which pixel is read when a kernel reaches past the edge of the image
+type EdgeMode int
+
+const (
+	EdgeClamp  EdgeMode = iota // repeat the edge pixel
+	EdgeMirror                 // reflect back into the image
+	EdgeWrap                   // carry on from the opposite edge
+)
+
+// EdgeModeOptions are the translation keys for each EdgeMode, for use as FilterParam.Options
+var EdgeModeOptions = []string{"control.edgemode.clamp", "control.edgemode.mirror", "control.edgemode.wrap"}
+
+// edgeIndex maps a coordinate that might be outside 0 to n-1 back inside it
+func edgeIndex(i, n int, mode EdgeMode) int {
+	if i >= 0 && i < n {
+		return i
+	}
+	switch mode {
+	case EdgeMirror:
+		if n == 1 {
+			return 0
+		}
+		// reflecting repeats every 2(n-1) pixels, without doubling up the edge pixel
+		period := 2 * (n - 1)
+		i = ((i % period) + period) % period
+		if i >= n {
+			i = period - i
+		}
+		return i
+	case EdgeWrap:
+		return ((i % n) + n) % n
+	default:
+		return min(max(i, 0), n-1)
+	}
+}
+
+// GaussianKernel makes a normalised 1D kernel that's 2*radius+1 long
+func GaussianKernel(radius int, sigma float64) []float32 {
+	kernel := make([]float32, 2*radius+1)
+	var sum float64
+	weights := make([]float64, len(kernel))
+	for i := range weights {
+		x := float64(i - radius)
+		weights[i] = math.Exp(-(x * x) / (2 * sigma * sigma))
+		sum += weights[i]
+	}
+	for i, w := range weights {
+		kernel[i] = float32(w / sum)
+	}
+	return kernel
+}
+
+// ConvolveSeparable convolves every channel of img with horizontal then vertical, both odd length and centred.
+// Doing it in two passes means a kernel of width n costs 2n reads per pixel rather than n*n.
+// It returns the context's error if ctx is cancelled part way through
+func ConvolveSeparable(ctx context.Context, img *image.RGBA, horizontal, vertical []float32, mode EdgeMode) error {
+	return convolveSeparable(ctx, rgbaPixels(img), horizontal, vertical, mode)
+}
+
+// convolveSeparable is ConvolveSeparable for an 8-bit or linear image
+func convolveSeparable[S sample](ctx context.Context, px pixels[S], horizontal, vertical []float32, mode EdgeMode) error {
+	w, h := px.Width, px.Height
+	if w == 0 || h == 0 {
+		return nil
+	}
+	// the horizontal pass is kept as floats so rounding only happens once
+	tmp := make([]float32, w*h*4)
+	hr := len(horizontal) / 2
+	err := ParallelRowsContext(ctx, h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := px.Pix[y*px.Stride:]
+			for x := 0; x < w; x++ {
+				var sums [4]float32
+				for k, weight := range horizontal {
+					i := edgeIndex(x+k-hr, w, mode) * 4
+					sums[0] += weight * float32(row[i+0])
+					sums[1] += weight * float32(row[i+1])
+					sums[2] += weight * float32(row[i+2])
+					sums[3] += weight * float32(row[i+3])
+				}
+				copy(tmp[(y*w+x)*4:], sums[:])
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	vr := len(vertical) / 2
+	return ParallelRowsContext(ctx, h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sums [4]float32
+				for k, weight := range vertical {
+					i := (edgeIndex(y+k-vr, h, mode)*w + x) * 4
+					sums[0] += weight * tmp[i+0]
+					sums[1] += weight * tmp[i+1]
+					sums[2] += weight * tmp[i+2]
+					sums[3] += weight * tmp[i+3]
+				}
+				i := y*px.Stride + x*4
+				px.Pix[i+0] = px.write(sums[0])
+				px.Pix[i+1] = px.write(sums[1])
+				px.Pix[i+2] = px.write(sums[2])
+				px.Pix[i+3] = px.write(sums[3])
+			}
+		}
+	})
+}
+
+// clampUint8 rounds v to the nearest value a channel can hold
+func clampUint8(v float32) uint8 {
+	return uint8(min(max(v+0.5, 0), 255))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// CropMode picks how the part of the image that's kept is given
+type CropMode int
+
+const (
+	CropFractions CropMode = iota // a fraction of the width or height off each side
+	CropPixels                    // a rectangle in pixels of the original image
+)
+
+// Cuts the image down, either by a fraction off each side or to a rectangle in pixels. Pixels are of the original
+// image so the shrunk preview and the full resolution export are cropped the same
+type CropFilter struct{}
+
+func (CropFilter) Name() string {
+	return "control.crop"
+}
+func (CropFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.crop.mode", Kind: ParamChoice, Min: 0, Max: float64(CropPixels), Default: float64(CropFractions), Options: []string{"control.crop.fractions", "control.crop.pixels"}},
+		{Key: "control.crop.left", Kind: ParamFloat, Min: 0, Max: 1, Default: 0},
+		{Key: "control.crop.top", Kind: ParamFloat, Min: 0, Max: 1, Default: 0},
+		{Key: "control.crop.right", Kind: ParamFloat, Min: 0, Max: 1, Default: 0},
+		{Key: "control.crop.bottom", Kind: ParamFloat, Min: 0, Max: 1, Default: 0},
+		{Key: "control.crop.x", Kind: ParamInt, Min: 0, Max: MaxImageSize, Default: 0},
+		{Key: "control.crop.y", Kind: ParamInt, Min: 0, Max: MaxImageSize, Default: 0},
+		// a width or height of 0 keeps everything up to the edge
+		{Key: "control.crop.width", Kind: ParamInt, Min: 0, Max: MaxImageSize, Default: 0},
+		{Key: "control.crop.height", Kind: ParamInt, Min: 0, Max: MaxImageSize, Default: 0},
+		sourceScaleParam,
+	}
+}
+func (CropFilter) Apply(img *image.RGBA, p Params) {
+	if CropMode(p.Int("control.crop.mode")) == CropPixels {
+		x, y := sourcePixels(p, p.Int("control.crop.x")), sourcePixels(p, p.Int("control.crop.y"))
+		width, height := img.Rect.Dx()-x, img.Rect.Dy()-y
+		if w := p.Int("control.crop.width"); w > 0 {
+			width = max(1, sourcePixels(p, w))
+		}
+		if h := p.Int("control.crop.height"); h > 0 {
+			height = max(1, sourcePixels(p, h))
+		}
+		*img = *CropRect(img, image.Rect(x, y, x+width, y+height))
+		return
+	}
+	*img = *Crop(img, p.Float("control.crop.left"), p.Float("control.crop.top"), p.Float("control.crop.right"), p.Float("control.crop.bottom"))
+}
+
+// Crop copies what's left of img after cutting off the given fraction of its width or height from each side.
+// At least one pixel is always kept
+func Crop(img *image.RGBA, left, top, right, bottom float64) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	x0 := Clamp(int(math.Round(left*float64(w))), 0, max(w-1, 0))
+	y0 := Clamp(int(math.Round(top*float64(h))), 0, max(h-1, 0))
+	x1 := Clamp(w-int(math.Round(right*float64(w))), x0+1, max(w, x0+1))
+	y1 := Clamp(h-int(math.Round(bottom*float64(h))), y0+1, max(h, y0+1))
+	rect := image.Rect(x0, y0, x1, y1).Add(img.Rect.Min).Intersect(img.Rect)
+	return ToRGBA(img.SubImage(rect))
+}
+
+// CropRect copies the part of img inside rect, which is measured from img's top left corner. Whatever of rect is
+// past the edges is left out, but at least one pixel is always kept
+func CropRect(img *image.RGBA, rect image.Rectangle) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	x0, y0 := Clamp(rect.Min.X, 0, max(w-1, 0)), Clamp(rect.Min.Y, 0, max(h-1, 0))
+	x1, y1 := Clamp(rect.Max.X, x0+1, max(w, x0+1)), Clamp(rect.Max.Y, y0+1, max(h, y0+1))
+	return ToRGBA(img.SubImage(image.Rect(x0, y0, x1, y1).Add(img.Rect.Min).Intersect(img.Rect)))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestCropFilter(t *testing.T) {
+	t.Run("Crop by fractions", func(t *testing.T) {
+		// Aim: a quarter off the left and half off the bottom of a 40x20 image leaves the top right 30x10
+		img := randomImage(40, 20)
+		res := Crop(img, 0.25, 0, 0, 0.5)
+		if res.Rect.Dx() != 30 || res.Rect.Dy() != 10 {
+			t.Fatalf("Expected a 30x10 image, got %v", res.Rect)
+		}
+		if !bytes.Equal(res.Pix[:4], img.Pix[10*4:10*4+4]) {
+			t.Error("Expected the crop to start at 10, 0")
+		}
+	})
+	t.Run("Cropping everything keeps a pixel", func(t *testing.T) {
+		// Aim: the sides meeting or crossing shouldn't give an empty image
+		res := Crop(randomImage(10, 10), 0.7, 0.7, 0.7, 0.7)
+		if res.Rect.Dx() != 1 || res.Rect.Dy() != 1 {
+			t.Errorf("Expected a 1x1 image, got %v", res.Rect)
+		}
+	})
+	t.Run("Crop by pixels", func(t *testing.T) {
+		// Aim: a 20x5 rectangle at 10, 2 of a 40x20 image should be exactly those pixels
+		img := randomImage(40, 20)
+		p := NewFilters().Params
+		p["control.crop.mode"] = float64(CropPixels)
+		p["control.crop.x"], p["control.crop.y"], p["control.crop.width"], p["control.crop.height"] = 10, 2, 20, 5
+		res := ToRGBA(img)
+		CropFilter{}.Apply(res, p)
+		if res.Rect.Dx() != 20 || res.Rect.Dy() != 5 {
+			t.Fatalf("Expected a 20x5 image, got %v", res.Rect)
+		}
+		if !bytes.Equal(res.Pix[:4], img.Pix[2*img.Stride+10*4:2*img.Stride+10*4+4]) {
+			t.Error("Expected the crop to start at 10, 2")
+		}
+	})
+	t.Run("Pixels are of the original image", func(t *testing.T) {
+		// Aim: on a preview a quarter of the size the same crop should cover a quarter of the pixels, and a width
+		// and height of 0 should go to the edge
+		p := NewFilters().Params
+		p["control.crop.mode"] = float64(CropPixels)
+		p["control.crop.x"], p["control.crop.y"] = 40, 20
+		p[SourceScaleKey] = 4
+		img := randomImage(40, 20)
+		CropFilter{}.Apply(img, p)
+		if img.Rect.Dx() != 30 || img.Rect.Dy() != 15 {
+			t.Errorf("Expected a 30x15 image, got %v", img.Rect)
+		}
+	})
+	t.Run("Rectangle past the edge", func(t *testing.T) {
+		// Aim: the part of the rectangle outside the image is left out, and one starting past it keeps a pixel
+		if res := CropRect(randomImage(10, 10), image.Rect(5, 5, 50, 50)); res.Rect.Dx() != 5 || res.Rect.Dy() != 5 {
+			t.Errorf("Expected a 5x5 image, got %v", res.Rect)
+		}
+		if res := CropRect(randomImage(10, 10), image.Rect(20, 20, 30, 30)); res.Rect.Dx() != 1 || res.Rect.Dy() != 1 {
+			t.Errorf("Expected a 1x1 image, got %v", res.Rect)
+		}
+	})
+	t.Run("Defaults leave the image alone", func(t *testing.T) {
+		img := randomImage(8, 8)
+		expected := ToRGBA(img)
+		CropFilter{}.Apply(img, NewFilters().Params)
+		if img.Rect != expected.Rect || !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Default crop changed the image")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"image"
+	"math"
+	"slices"
+)
+
+// MaxCurvePoints is the most control points a channel's curve can have
+const MaxCurvePoints = 16
+
+// CurvePoint is a control point of a curve, both from 0 to 255
+type CurvePoint struct {
+	X, Y float64
+}
+
+// Curves are the control points of every channel's curve in ToneChannel order, a channel without any is a straight
+// line from black to white
+type Curves [ToneBlue + 1][]CurvePoint
+
+// Curve is a copy of a channel's control points, a straight line from black to white if it hasn't been set
+func (c Curves) Curve(channel ToneChannel) []CurvePoint {
+	if c[channel] == nil {
+		return []CurvePoint{{0, 0}, {255, 255}}
+	}
+	return slices.Clone(c[channel][:min(len(c[channel]), MaxCurvePoints)])
+}
+
+// Clone copies every channel's points so the copy can be changed without affecting the original
+func (c Curves) Clone() Curves {
+	for channel := range c {
+		c[channel] = slices.Clone(c[channel])
+	}
+	return c
+}
+
+// Curves with draggable control points for every channel, edited from the levels and curves window. The points are
+// kept in Filters.Curves rather than in parameters
+type CurvesFilter struct{}
+
+func (CurvesFilter) Name() string {
+	return "control.curves"
+}
+func (CurvesFilter) Params() []FilterParam {
+	return nil
+}
+func (f CurvesFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyFilters(context.Background(), img, Filters{Params: p})
+}
+func (CurvesFilter) ApplyFilters(ctx context.Context, img *image.RGBA, f Filters) error {
+	r, g, b := toneLUTs(func(c ToneChannel) LUT { return CurveLUT(f.Curves.Curve(c)) })
+	ApplyLUTs(img, r, g, b)
+	return nil
+}
+
+// CurveLUT interpolates between the control points with a monotone cubic spline, which goes smoothly through every
+// point without overshooting between them. Before the first point and after the last the curve is flat.
+// No points leaves every value alone
+func CurveLUT(points []CurvePoint) LUT {
+	// sort the points, and where points share an x only keep the last of them
+	sorted := slices.Clone(points)
+	slices.SortStableFunc(sorted, func(a, b CurvePoint) int { return cmp.Compare(a.X, b.X) })
+	points = sorted[:0]
+	for _, point := range sorted {
+		if len(points) > 0 && points[len(points)-1].X == point.X {
+			points[len(points)-1] = point
+			continue
+		}
+		points = append(points, point)
+	}
+	if len(points) == 0 {
+		return IdentityLUT()
+	}
+
+	// slopes between points, then the tangent at each point from the Fritsch-Carlson method
+	n := len(points)
+	slopes := make([]float64, max(n-1, 0))
+	for k := range slopes {
+		slopes[k] = (points[k+1].Y - points[k].Y) / (points[k+1].X - points[k].X)
+	}
+	tangents := make([]float64, n)
+	if n > 1 {
+		tangents[0], tangents[n-1] = slopes[0], slopes[n-2]
+	}
+	for k := 1; k < n-1; k++ {
+		// a point where the curve turns around is a peak or a trough, so it's flat there
+		if slopes[k-1]*slopes[k] > 0 {
+			tangents[k] = (slopes[k-1] + slopes[k]) / 2
+		}
+	}
+	for k, slope := range slopes {
+		if slope == 0 {
+			tangents[k], tangents[k+1] = 0, 0
+			continue
+		}
+		// scale tangents that are too steep for the segment down so it can't overshoot
+		a, b := tangents[k]/slope, tangents[k+1]/slope
+		if s := a*a + b*b; s > 9 {
+			t := 3 / math.Sqrt(s)
+			tangents[k], tangents[k+1] = t*a*slope, t*b*slope
+		}
+	}
+
+	var l LUT
+	k := 0
+	for i := range l {
+		x := float64(i)
+		var y float64
+		switch {
+		case x <= points[0].X:
+			y = points[0].Y
+		case x >= points[n-1].X:
+			y = points[n-1].Y
+		default:
+			for x > points[k+1].X {
+				k++
+			}
+			// cubic Hermite between points k and k+1
+			h := points[k+1].X - points[k].X
+			t := (x - points[k].X) / h
+			t2, t3 := t*t, t*t*t
+			y = (2*t3-3*t2+1)*points[k].Y + (t3-2*t2+t)*h*tangents[k] + (-2*t3+3*t2)*points[k+1].Y + (t3-t2)*h*tangents[k+1]
+		}
+		l[i] = uint8(math.Round(Clamp(y, 0, 255)))
+	}
+	return l
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCurvesFilter(t *testing.T) {
+	t.Run("Default curves leave the image alone", func(t *testing.T) {
+		img := randomImage(16, 16)
+		expected := ToRGBA(img)
+		CurvesFilter{}.Apply(img, NewFilters().Params)
+		if !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Default curves changed the image")
+		}
+	})
+	t.Run("Curve goes through every point", func(t *testing.T) {
+		// Aim: the spline should hit the control points exactly, in whatever order they're given
+		points := []CurvePoint{{255, 200}, {0, 30}, {64, 100}, {192, 120}}
+		l := CurveLUT(points)
+		for _, p := range points {
+			if l[int(p.X)] != uint8(p.Y) {
+				t.Errorf("Expected %v to be on the curve, got %d", p, l[int(p.X)])
+			}
+		}
+	})
+	t.Run("No overshoot", func(t *testing.T) {
+		// Aim: a curve through rising points should never go back down or past the points either side of it
+		l := CurveLUT([]CurvePoint{{0, 0}, {60, 10}, {70, 245}, {255, 255}})
+		if !slices.IsSorted(l[:]) {
+			t.Errorf("Curve through rising points wasn't rising: %v", l)
+		}
+		flat := CurveLUT([]CurvePoint{{0, 0}, {100, 200}, {150, 200}, {255, 255}})
+		for v := 100; v <= 150; v++ {
+			if flat[v] != 200 {
+				t.Errorf("Expected the curve between two equal points to stay flat, got %d at %d", flat[v], v)
+			}
+		}
+	})
+	t.Run("Flat past the end points", func(t *testing.T) {
+		// Aim: before the first point and after the last the curve should stay at their values
+		l := CurveLUT([]CurvePoint{{50, 20}, {200, 220}})
+		if l[0] != 20 || l[49] != 20 || l[201] != 220 || l[255] != 220 {
+			t.Errorf("Expected the ends to be flat, got %d %d %d %d", l[0], l[49], l[201], l[255])
+		}
+	})
+	t.Run("Only the channel's own curve is applied", func(t *testing.T) {
+		// Aim: a curve set on one channel only changes that channel, the others stay straight lines
+		f := NewFilters()
+		points := []CurvePoint{{0, 255}, {128, 64}, {255, 0}}
+		f.Curves[ToneGreen] = points
+		if got := f.Curves.Curve(ToneRed); !slices.Equal(got, []CurvePoint{{0, 0}, {255, 255}}) {
+			t.Errorf("Expected red to still be a straight line, got %v", got)
+		}
+		img := randomImage(4, 4)
+		original := ToRGBA(img)
+		if err := (CurvesFilter{}).ApplyFilters(context.Background(), img, f); err != nil {
+			t.Fatal(err)
+		}
+		green := CurveLUT(points)
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != original.Pix[i] || img.Pix[i+1] != green[original.Pix[i+1]] || img.Pix[i+3] != original.Pix[i+3] {
+				t.Fatalf("Pixel %d: expected only green to go through the curve", i/4)
+			}
+		}
+	})
+	t.Run("Curves are part of the filters", func(t *testing.T) {
+		// Aim: the points aren't parameters, but cloning, the cache key and saved projects all have to include them
+		f := NewFilters()
+		f.Curves[ToneAll] = []CurvePoint{{0, 20}, {255, 200}}
+		clone := f.Clone()
+		clone.Curves[ToneAll][0].Y = 40
+		if f.Curves[ToneAll][0].Y != 20 {
+			t.Error("Changing a clone's curve changed the original")
+		}
+		if stageKey(CurvesFilter{}, f) == stageKey(CurvesFilter{}, clone) {
+			t.Error("Changing the curve didn't change the stage key")
+		}
+		if got := f.WithDefaults().Curves.Curve(ToneAll); !slices.Equal(got, f.Curves[ToneAll]) {
+			t.Errorf("Expected the curve to be kept, got %v", got)
+		}
+	})
+}
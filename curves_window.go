@@ -0,0 +1,201 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	gui "github.com/gen2brain/raylib-go/raygui"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// curveGraphSize is how big the curve editor is on screen, it covers values 0 to 255 along both sides
+const curveGraphSize = 320
+
+// CurvesWindow is where levels are set and curves are drawn over the histogram, one channel at a time
+type CurvesWindow struct {
+	Showing        bool
+	Anchor         rl.Vector2
+	InteractedWith time.Time
+	ActiveChannel  int32
+	// Index of the control point being dragged, -1 if there isn't one
+	Dragging int
+}
+
+func (c *CurvesWindow) getRect() rl.Rectangle {
+	return rl.NewRectangle(c.Anchor.X, c.Anchor.Y, 340, 540)
+}
+
+// channelColour is what the active channel's histogram and curve are drawn in
+func (c *CurvesWindow) channelColour() rl.Color {
+	return []rl.Color{rl.DarkGray, rl.Red, rl.Green, rl.Blue}[c.ActiveChannel]
+}
+
+// histogram is the active channel's histogram, every channel added together for ToneAll
+func (c *CurvesWindow) histogram() []int {
+	switch ToneChannel(c.ActiveChannel) {
+	case ToneRed:
+		return state.RedHistogram[:]
+	case ToneGreen:
+		return state.GreenHistogram[:]
+	case ToneBlue:
+		return state.BlueHistogram[:]
+	}
+	total := make([]int, 256)
+	for i := range total {
+		total[i] = state.RedHistogram[i] + state.GreenHistogram[i] + state.BlueHistogram[i]
+	}
+	return total
+}
+
+// levelSlider draws a slider for one of the active channel's levels
+func (c *CurvesWindow) levelSlider(y float32, setting string) {
+	key := LevelsKey(ToneChannel(c.ActiveChannel), setting)
+	params := LevelsFilter{}.Params()
+	p := params[slices.IndexFunc(params, func(p FilterParam) bool { return p.Key == key })]
+	value := state.Filters.Params[key]
+	label := fmt.Sprintf("%s: %d", Translate("control.levels."+setting), int(value))
+	if p.Kind == ParamFloat {
+		label = fmt.Sprintf("%s: %.2f", Translate("control.levels."+setting), value)
+	}
+	value = float64(gui.Slider(rl.NewRectangle(c.Anchor.X+10, y, 200, 20), "", label, float32(value), float32(p.Min), float32(p.Max)))
+	if p.Kind == ParamInt {
+		value = math.Trunc(value)
+	}
+	if value != state.Filters.Params[key] {
+		state.Filters.Params[key] = value
+		state.Filters.Enabled[LevelsFilter{}.Name()] = true
+	}
+}
+
+// Draw the levels and curves window
+func (c *CurvesWindow) Draw() {
+	c.Showing = !gui.WindowBox(c.getRect(), Translate("window.curves.title"))
+	channel := ToneChannel(c.ActiveChannel)
+	c.ActiveChannel = gui.ToggleGroup(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+30, 78, 20), strings.Join(MapOut(ToneChannelNames, Translate), ";"), c.ActiveChannel)
+	if ToneChannel(c.ActiveChannel) != channel {
+		c.Dragging = -1
+		channel = ToneChannel(c.ActiveChannel)
+	}
+
+	// Levels
+	levels := LevelsFilter{}.Name()
+	state.Filters.Enabled[levels] = gui.CheckBox(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+60, 10, 10), Translate(levels), state.Filters.Enabled[levels])
+	c.levelSlider(c.Anchor.Y+80, "black")
+	c.levelSlider(c.Anchor.Y+105, "white")
+	c.levelSlider(c.Anchor.Y+130, "gamma")
+	// keep the white point above the black point
+	black, white := LevelsKey(channel, "black"), LevelsKey(channel, "white")
+	state.Filters.Params[white] = max(state.Filters.Params[white], state.Filters.Params[black]+1)
+
+	// Curves
+	curves := CurvesFilter{}.Name()
+	state.Filters.Enabled[curves] = gui.CheckBox(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+165, 10, 10), Translate(curves), state.Filters.Enabled[curves])
+	if gui.Button(rl.NewRectangle(c.Anchor.X+230, c.Anchor.Y+160, 100, 20), Translate("window.curves.reset")) {
+		state.Filters.Curves[channel] = nil
+		SetLevels(state.Filters.Params, channel, 0, 255, 1)
+	}
+	c.DrawCurve(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+185, curveGraphSize, curveGraphSize))
+	gui.Label(rl.NewRectangle(c.Anchor.X+10, c.Anchor.Y+510, 320, 20), Translate("window.curves.hint"))
+}
+
+// DrawCurve draws the active channel's histogram with its curve on top, and lets the control points be added,
+// dragged and removed
+func (c *CurvesWindow) DrawCurve(bounds rl.Rectangle) {
+	channel := ToneChannel(c.ActiveChannel)
+	scale := bounds.Width / 256
+	// screen position of a value, y goes up from the bottom
+	toScreen := func(p CurvePoint) rl.Vector2 {
+		return rl.NewVector2(bounds.X+float32(p.X)*scale, bounds.Y+bounds.Height-float32(p.Y)*scale)
+	}
+	rl.DrawRectangleRec(bounds, rl.RayWhite)
+
+	// histogram behind everything
+	data := c.histogram()
+	if largest := slices.Max(data); largest > 0 {
+		colour := rl.Fade(c.channelColour(), 0.25)
+		for i, count := range data {
+			height := bounds.Height * float32(count) / float32(largest)
+			rl.DrawRectangleRec(rl.NewRectangle(bounds.X+float32(i)*scale, bounds.Y+bounds.Height-height, scale, height), colour)
+		}
+	}
+	// quarter lines and the straight line the curve starts as
+	for i := float32(1); i < 4; i++ {
+		rl.DrawLineV(rl.NewVector2(bounds.X+bounds.Width*i/4, bounds.Y), rl.NewVector2(bounds.X+bounds.Width*i/4, bounds.Y+bounds.Height), rl.LightGray)
+		rl.DrawLineV(rl.NewVector2(bounds.X, bounds.Y+bounds.Height*i/4), rl.NewVector2(bounds.X+bounds.Width, bounds.Y+bounds.Height*i/4), rl.LightGray)
+	}
+	rl.DrawLineV(toScreen(CurvePoint{0, 0}), toScreen(CurvePoint{255, 255}), rl.LightGray)
+	rl.DrawRectangleLinesEx(bounds, 1, rl.Gray)
+
+	// the curve as it's actually applied
+	points := state.Filters.Curves.Curve(channel)
+	lut := CurveLUT(points)
+	strip := make([]rl.Vector2, len(lut))
+	for i, v := range lut {
+		strip[i] = toScreen(CurvePoint{float64(i), float64(v)})
+	}
+	rl.DrawLineStrip(strip, c.channelColour())
+
+	// find the point under the mouse, if any
+	mouse := rl.GetMousePosition()
+	hovered := -1
+	for i, p := range points {
+		if rl.CheckCollisionPointCircle(mouse, toScreen(p), 6) {
+			hovered = i
+		}
+	}
+	// value under the mouse, clamped to the graph
+	value := func() CurvePoint {
+		return CurvePoint{
+			X: math.Round(Clamp(float64((mouse.X-bounds.X)/scale), 0, 255)),
+			Y: math.Round(Clamp(float64((bounds.Y+bounds.Height-mouse.Y)/scale), 0, 255)),
+		}
+	}
+
+	switch {
+	case rl.IsMouseButtonPressed(rl.MouseLeftButton) && hovered >= 0:
+		c.Dragging = hovered
+	case rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, bounds) && len(points) < MaxCurvePoints:
+		// add a point where the mouse is and start dragging it, keeping the points in order
+		point := value()
+		i, found := slices.BinarySearchFunc(points, point.X, func(p CurvePoint, x float64) int { return cmp.Compare(p.X, x) })
+		if !found {
+			points = slices.Insert(points, i, point)
+			state.Filters.Curves[channel] = points
+			state.Filters.Enabled[CurvesFilter{}.Name()] = true
+		}
+		c.Dragging = i
+	case rl.IsMouseButtonPressed(rl.MouseRightButton) && hovered >= 0 && len(points) > 2:
+		points = slices.Delete(points, hovered, hovered+1)
+		state.Filters.Curves[channel] = points
+		state.Filters.Enabled[CurvesFilter{}.Name()] = true
+	}
+	if !rl.IsMouseButtonDown(rl.MouseLeftButton) {
+		c.Dragging = -1
+	}
+	// only move the point once the mouse moves, so clicking a point doesn't nudge it to where the mouse is
+	if c.Dragging >= 0 && c.Dragging < len(points) && rl.GetMouseDelta() != (rl.Vector2{}) {
+		// points can't be dragged past their neighbours so they stay in order
+		point := value()
+		if c.Dragging > 0 {
+			point.X = max(point.X, points[c.Dragging-1].X+1)
+		}
+		if c.Dragging < len(points)-1 {
+			point.X = min(point.X, points[c.Dragging+1].X-1)
+		}
+		points[c.Dragging] = point
+		state.Filters.Curves[channel] = points
+		state.Filters.Enabled[CurvesFilter{}.Name()] = true
+	}
+
+	for i, p := range points {
+		centre := toScreen(p)
+		rl.DrawCircleV(centre, 4, c.channelColour())
+		if i == hovered || i == c.Dragging {
+			rl.DrawCircleLines(int32(centre.X), int32(centre.Y), 6, rl.Black)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"image"
+)
+
+// DiffusionAlgorithm picks which error diffusion matrix is used
+type DiffusionAlgorithm int
+
+const (
+	FloydSteinberg DiffusionAlgorithm = iota
+	Atkinson
+	JarvisJudiceNinke
+	Stucki
+	Burkes
+	Sierra
+	SierraLite
+)
+
+// diffusionWeight is how much of a pixel's error goes to the pixel dx across and dy down from it
+type diffusionWeight struct {
+	dx, dy int
+	weight float32
+}
+
+// diffusionMatrices hold the weights of each algorithm, already divided by the matrix's divisor
+var diffusionMatrices = map[DiffusionAlgorithm][]diffusionWeight{
+	FloydSteinberg: divideWeights(16, []diffusionWeight{
+		{1, 0, 7},
+		{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+	}),
+	// Atkinson only passes on 6/8 of the error, which keeps more contrast
+	Atkinson: divideWeights(8, []diffusionWeight{
+		{1, 0, 1}, {2, 0, 1},
+		{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+		{0, 2, 1},
+	}),
+	JarvisJudiceNinke: divideWeights(48, []diffusionWeight{
+		{1, 0, 7}, {2, 0, 5},
+		{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+		{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+	}),
+	Stucki: divideWeights(42, []diffusionWeight{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+		{-2, 2, 1}, {-1, 2, 2}, {0, 2, 4}, {1, 2, 2}, {2, 2, 1},
+	}),
+	Burkes: divideWeights(32, []diffusionWeight{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+	}),
+	Sierra: divideWeights(32, []diffusionWeight{
+		{1, 0, 5}, {2, 0, 3},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+		{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+	}),
+	SierraLite: divideWeights(4, []diffusionWeight{
+		{1, 0, 2},
+		{-1, 1, 1}, {0, 1, 1},
+	}),
+}
+
+func divideWeights(divisor float32, weights []diffusionWeight) []diffusionWeight {
+	for i := range weights {
+		weights[i].weight /= divisor
+	}
+	return weights
+}
+
+// Error diffusion dithering
+type DitheringFilter struct{}
+
+func (DitheringFilter) Name() string {
+	return "control.dithering"
+}
+func (DitheringFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.dithering.buckets", Kind: ParamInt, Min: 2, Max: 15, Default: 15},
+		{Key: "control.dithering.algorithm", Kind: ParamChoice, Min: 0, Max: float64(SierraLite), Default: float64(FloydSteinberg), Options: []string{
+			"control.dithering.floydsteinberg",
+			"control.dithering.atkinson",
+			"control.dithering.jarvisjudiceninke",
+			"control.dithering.stucki",
+			"control.dithering.burkes",
+			"control.dithering.sierra",
+			"control.dithering.sierralite",
+		}},
+		{Key: "control.dithering.scan", Kind: ParamChoice, Min: 0, Max: 1, Default: 0, Options: []string{"control.dithering.raster", "control.dithering.serpentine"}},
+		linearLightParam,
+	}
+}
+func (f DitheringFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f DitheringFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	return dither(ctx, rgbaPixels(img), p)
+}
+func (DitheringFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	return dither(ctx, img.pixels(), f.Params)
+}
+
+func dither[S sample](ctx context.Context, px pixels[S], p Params) error {
+	buckets := uint8(p.Int("control.dithering.buckets"))
+	return diffuseError(ctx, px, DiffusionAlgorithm(p.Int("control.dithering.algorithm")), p.Int("control.dithering.scan") == 1, func(c [3]float32) [3]uint8 {
+		return [3]uint8{Quantize(buckets, clampUint8(c[0])), Quantize(buckets, clampUint8(c[1])), Quantize(buckets, clampUint8(c[2]))}
+	})
+}
+
+// DiffuseError replaces each pixel with the colour quantize picks for it and spreads the difference over the
+// pixels that haven't been done yet, quantize is given the pixel with the error it's been sent so far.
+// Every pixel depends on the ones before it so it can't be split into bands, instead ctx is checked every row
+// and its error is returned if it's been cancelled
+func DiffuseError(ctx context.Context, img *image.RGBA, algorithm DiffusionAlgorithm, serpentine bool, quantize func(c [3]float32) [3]uint8) error {
+	return diffuseError(ctx, rgbaPixels(img), algorithm, serpentine, quantize)
+}
+
+// diffuseError is DiffuseError for an 8-bit or linear image. In linear light the error is measured and spread in
+// linear light, quantize is still given sRGB values
+func diffuseError[S sample](ctx context.Context, px pixels[S], algorithm DiffusionAlgorithm, serpentine bool, quantize func(c [3]float32) [3]uint8) error {
+	matrix := diffusionMatrices[algorithm]
+	w, h := px.Width, px.Height
+
+	// the error is kept signed and unrounded separately from the image so it isn't lost to clamping
+	// until the pixel it ends up in is quantized
+	values := make([]float32, w*h*3)
+	for y := range h {
+		for x := range w {
+			i := y*px.Stride + x*4
+			j := (y*w + x) * 3
+			values[j+0] = float32(px.Pix[i+0])
+			values[j+1] = float32(px.Pix[i+1])
+			values[j+2] = float32(px.Pix[i+2])
+		}
+	}
+
+	for y := range h {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// serpentine scanning goes back the other way on every other row, which stops the error
+		// piling up in one direction
+		reverse := serpentine && y%2 == 1
+		for n := range w {
+			x, dir := n, 1
+			if reverse {
+				x, dir = w-1-n, -1
+			}
+			i := y*px.Stride + x*4
+			j := (y*w + x) * 3
+			old := [3]float32(values[j : j+3])
+			wanted := old
+			if px.Linear {
+				for c := range 3 {
+					wanted[c] = float32(fromLinearScale(old[c]))
+				}
+			}
+			quantized := quantize(wanted)
+			for c := range 3 {
+				px.Pix[i+c] = px.fromSRGB(quantized[c])
+				diff := old[c] - float32(px.Pix[i+c])
+				for _, d := range matrix {
+					nx, ny := x+d.dx*dir, y+d.dy
+					if nx < 0 || nx >= w || ny >= h {
+						continue
+					}
+					values[(ny*w+nx)*3+c] += diff * d.weight
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestDitheringFilter(t *testing.T) {
+	params := func(algorithm DiffusionAlgorithm, serpentine bool) Params {
+		p := Params{
+			"control.dithering.buckets":   2,
+			"control.dithering.algorithm": float64(algorithm),
+		}
+		if serpentine {
+			p["control.dithering.scan"] = 1
+		}
+		return p
+	}
+	grey := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+		for i := range img.Pix {
+			img.Pix[i] = 120
+		}
+		return img
+	}
+	mean := func(img *image.RGBA) float64 {
+		var sum float64
+		for i := 0; i < len(img.Pix); i += 4 {
+			sum += float64(img.Pix[i])
+		}
+		return sum / float64(len(img.Pix)/4)
+	}
+
+	t.Run("Weights", func(t *testing.T) {
+		// Aim: every algorithm passes on all of the error apart from Atkinson which passes on 3/4
+		for algorithm, matrix := range diffusionMatrices {
+			var sum float32
+			for _, d := range matrix {
+				sum += d.weight
+				if d.dy < 0 || (d.dy == 0 && d.dx <= 0) {
+					t.Errorf("Algorithm %d sends error to a pixel that's already been done", algorithm)
+				}
+			}
+			expected := float32(1)
+			if algorithm == Atkinson {
+				expected = 0.75
+			}
+			if math.Abs(float64(sum-expected)) > 1e-6 {
+				t.Errorf("Algorithm %d weights sum to %v, expected %v", algorithm, sum, expected)
+			}
+		}
+	})
+	t.Run("Average is kept", func(t *testing.T) {
+		// Aim: diffusing the error should keep the overall brightness, and only quantized levels should come out
+		for algorithm := range diffusionMatrices {
+			if algorithm == Atkinson {
+				continue
+			}
+			for _, serpentine := range []bool{false, true} {
+				img := grey()
+				DitheringFilter{}.Apply(img, params(algorithm, serpentine))
+				if m := mean(img); math.Abs(m-120) > 4 {
+					t.Errorf("Algorithm %d (serpentine %v): mean changed from 120 to %v", algorithm, serpentine, m)
+				}
+				for i := 0; i < len(img.Pix); i += 4 {
+					if v := img.Pix[i]; v != Quantize(2, v) {
+						t.Fatalf("Algorithm %d left an unquantized value %d", algorithm, v)
+					}
+				}
+			}
+		}
+	})
+	t.Run("Serpentine changes the pattern", func(t *testing.T) {
+		raster, serpentine := grey(), grey()
+		DitheringFilter{}.Apply(raster, params(FloydSteinberg, false))
+		DitheringFilter{}.Apply(serpentine, params(FloydSteinberg, true))
+		if bytes.Equal(raster.Pix, serpentine.Pix) {
+			t.Error("Serpentine scanning gave the same result as left to right")
+		}
+		// the first row is scanned the same way either way
+		if !bytes.Equal(raster.Pix[:raster.Stride], serpentine.Pix[:serpentine.Stride]) {
+			t.Error("The first row should be the same")
+		}
+	})
+	t.Run("Large errors aren't wrapped", func(t *testing.T) {
+		// Aim: white stays white, with uint8 errors the error from clamping used to wrap around to dark pixels
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		DitheringFilter{}.Apply(img, params(JarvisJudiceNinke, true))
+		for i, v := range img.Pix {
+			if v != 255 {
+				t.Fatalf("Channel %d changed to %d", i, v)
+			}
+		}
+	})
+}
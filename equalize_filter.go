@@ -0,0 +1,123 @@
+package main
+
+import (
+	"image"
+	"math"
+	"slices"
+)
+
+// EqualizeChannels picks what histogram equalization works on
+type EqualizeChannels int
+
+const (
+	EqualizeRGB       EqualizeChannels = iota // red, green and blue are each equalized on their own, which can shift colours
+	EqualizeLuminance                         // only the Rec. 601 luma is equalized, the same amount is added to every channel
+)
+
+// equalizeChannelsParam is the choice of channels, key is the stage's parameter key
+func equalizeChannelsParam(key string) FilterParam {
+	return FilterParam{Key: key, Kind: ParamChoice, Min: 0, Max: float64(EqualizeLuminance), Default: float64(EqualizeRGB), Options: []string{
+		"control.equalize.channels.rgb",
+		"control.equalize.channels.luminance",
+	}}
+}
+
+// Global histogram equalization, which spreads the values out so every level is used about as much as any other
+type EqualizeFilter struct{}
+
+func (EqualizeFilter) Name() string {
+	return "control.equalize"
+}
+func (EqualizeFilter) Params() []FilterParam {
+	return []FilterParam{equalizeChannelsParam("control.equalize.channels")}
+}
+func (EqualizeFilter) Apply(img *image.RGBA, p Params) {
+	equalizePlanes(img, EqualizeChannels(p.Int("control.equalize.channels")), func(plane []uint8, _, _ int) {
+		var hist [256]int
+		for _, v := range plane {
+			hist[v]++
+		}
+		lut := EqualizeLUT(hist, true)
+		for i, v := range plane {
+			plane[i] = lut[v]
+		}
+	})
+}
+
+// EqualizeLUT is the table that equalizes hist, from its cumulative histogram. Stretching maps the darkest value
+// there is to black, which is what global equalization does. A histogram with one value or none leaves everything alone
+func EqualizeLUT(hist [256]int, stretch bool) LUT {
+	var cdf [256]int
+	total := 0
+	for v, count := range hist {
+		total += count
+		cdf[v] = total
+	}
+	lowest := 0
+	if stretch {
+		if first := slices.IndexFunc(hist[:], func(count int) bool { return count > 0 }); first >= 0 {
+			lowest = cdf[first]
+		}
+	}
+	if total == lowest {
+		return IdentityLUT()
+	}
+	var l LUT
+	for v := range l {
+		l[v] = uint8(math.Round(Clamp(float64(cdf[v]-lowest)*255/float64(total-lowest), 0, 255)))
+	}
+	return l
+}
+
+// equalizePlanes copies the planes channels picks out of img, has equalize change each one in place and puts them back
+func equalizePlanes(img *image.RGBA, channels EqualizeChannels, equalize func(plane []uint8, width, height int)) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	count := 3
+	if channels == EqualizeLuminance {
+		count = 1
+	}
+	planes := make([][]uint8, count)
+	for c := range planes {
+		planes[c] = make([]uint8, w*h)
+	}
+	ParallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range w {
+				i := y*img.Stride + x*4
+				if channels == EqualizeLuminance {
+					planes[0][y*w+x] = Grey(GrayscaleRec601, img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], false)
+					continue
+				}
+				for c, plane := range planes {
+					plane[y*w+x] = img.Pix[i+c]
+				}
+			}
+		}
+	})
+	// the luma before equalizing, to work out how much each pixel changed
+	var luma []uint8
+	if channels == EqualizeLuminance {
+		luma = slices.Clone(planes[0])
+	}
+	for _, plane := range planes {
+		equalize(plane, w, h)
+	}
+	ParallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range w {
+				i := y*img.Stride + x*4
+				if channels == EqualizeLuminance {
+					// moving every channel by the same amount moves luma by that amount without touching Cb or Cr
+					change := int(planes[0][y*w+x]) - int(luma[y*w+x])
+					for c := range 3 {
+						img.Pix[i+c] = uint8(Clamp(int(img.Pix[i+c])+change, 0, 255))
+					}
+					continue
+				}
+				for c, plane := range planes {
+					img.Pix[i+c] = plane[y*w+x]
+				}
+			}
+		}
+	})
+}
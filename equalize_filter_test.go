@@ -0,0 +1,57 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+// flatImage is a w by h grey gradient squashed into the values from low to high
+func flatImage(w, h int, low, high uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		v := low + uint8((i/4)*int(high-low+1)/(w*h))
+		img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 255
+	}
+	return img
+}
+
+func TestEqualizeFilter(t *testing.T) {
+	t.Run("Equalizing stretches a flat image", func(t *testing.T) {
+		// Aim: a scan that only uses 100 to 150 should use the whole range afterwards, with the darkest at black
+		img := flatImage(32, 32, 100, 150)
+		p := NewFilters().Params
+		EqualizeFilter{}.Apply(img, p)
+		lowest, highest := uint8(255), uint8(0)
+		for i := 0; i < len(img.Pix); i += 4 {
+			lowest, highest = min(lowest, img.Pix[i]), max(highest, img.Pix[i])
+		}
+		if lowest != 0 || highest != 255 {
+			t.Errorf("Expected the range to become 0 to 255, got %d to %d", lowest, highest)
+		}
+	})
+	t.Run("A single value is left alone", func(t *testing.T) {
+		// Aim: there's nothing to spread out, so an image of one colour shouldn't turn black
+		var hist [256]int
+		hist[80] = 100
+		if l := EqualizeLUT(hist, true); l != IdentityLUT() {
+			t.Errorf("Expected a single value to leave the table alone, 80 became %d", l[80])
+		}
+	})
+	t.Run("Luminance only keeps greys grey and colours in order", func(t *testing.T) {
+		// Aim: every channel moves by the same amount, so greys stay grey and no channel overtakes another
+		img := flatImage(32, 32, 100, 150)
+		for i := 0; i < len(img.Pix); i += 8 {
+			img.Pix[i+0] += 10
+			img.Pix[i+2] -= 10
+		}
+		p := NewFilters().Params
+		p["control.equalize.channels"] = float64(EqualizeLuminance)
+		EqualizeFilter{}.Apply(img, p)
+		for i := 0; i < len(img.Pix); i += 4 {
+			r, g, b := img.Pix[i+0], img.Pix[i+1], img.Pix[i+2]
+			if i%8 == 0 && (r < g || g < b) || i%8 != 0 && (r != g || g != b) {
+				t.Fatalf("Expected pixel %d to keep its colour, got %d, %d, %d", i/4, r, g, b)
+			}
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"image"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Filter is a single stage of the image pipeline
+type Filter interface {
+	// Name is the translation key of the filter, it's also used as the key in Filters.Order and Filters.Enabled
+	Name() string
+	// Params describes the parameters the UI should show for the filter
+	Params() []FilterParam
+	// Apply the filter in place to img using the values in p. Filters that change the size of the image, like
+	// cropping or rotating, replace *img with the new image
+	Apply(img *image.RGBA, p Params)
+}
+
+// ContextFilter is a filter slow enough at full resolution that it checks for cancellation part way through
+// rather than only between stages
+type ContextFilter interface {
+	Filter
+	// ApplyContext is Apply but it returns the context's error as soon as it sees ctx has been cancelled, img is
+	// left part done when it does
+	ApplyContext(ctx context.Context, img *image.RGBA, p Params) error
+}
+
+// StructuredFilter is a filter with settings that aren't single numbers, like the palette, so they're kept in their
+// own fields of Filters rather than in Params
+type StructuredFilter interface {
+	Filter
+	// ApplyFilters is ApplyContext given every filter setting rather than just the parameters, Apply and
+	// ApplyContext only see the parameters so they act as if the structured settings were empty
+	ApplyFilters(ctx context.Context, img *image.RGBA, f Filters) error
+}
+
+// LinearFilter is a filter that mixes pixels together and honours LinearLightKey. With linear light on the pipeline
+// gives it a LinearImage, so a run of them is only converted to and from linear light once
+type LinearFilter interface {
+	Filter
+	// ApplyLinear is ApplyFilters for an image in linear light. Apply still has to honour linear light on its own
+	// for when the filter is used outside the pipeline
+	ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error
+}
+
+// applyFilter applies filter to img with the settings in f, through ApplyFilters or ApplyContext for the filters
+// that have them
+func applyFilter(ctx context.Context, filter Filter, img *image.RGBA, f Filters) error {
+	switch filter := filter.(type) {
+	case StructuredFilter:
+		return filter.ApplyFilters(ctx, img, f)
+	case ContextFilter:
+		return filter.ApplyContext(ctx, img, f.Params)
+	}
+	filter.Apply(img, f.Params)
+	return nil
+}
+
+type ParamKind int32
+
+const (
+	ParamFloat ParamKind = iota
+	ParamInt             = iota
+	// ParamChoice is an index into FilterParam.Options
+	ParamChoice = iota
+)
+
+// FilterParam describes a single adjustable value of a filter
+type FilterParam struct {
+	Key     string // translation key, also the key into Filters.Params
+	Kind    ParamKind
+	Min     float64
+	Max     float64
+	Default float64
+	// Translation keys of each choice for ParamChoice parameters
+	Options []string
+	// Hidden parameters don't get a slider or command line flag, either because they're edited from the filter's
+	// own window or because they belong to another filter and are only listed so the filter's cache key includes them
+	Hidden bool
+}
+
+// Params holds the current value of every filter parameter, keyed by FilterParam.Key
+type Params map[string]float64
+
+func (p Params) Float(key string) float64 {
+	return p[key]
+}
+func (p Params) Int(key string) int {
+	return int(math.Trunc(p[key]))
+}
+
+// PixelScaleKey is the parameter holding how many pixels of the image being filtered match one pixel of the preview
+// the filters were set up on. Sizes in pixels are multiplied by it so a full resolution export looks like the
+// preview. Dithering is left at single pixels on purpose
+const PixelScaleKey = "control.pixelscale"
+
+// pixelScaleParam is added to the parameters of every filter with a size in pixels, so its cache key includes it
+var pixelScaleParam = FilterParam{Key: PixelScaleKey, Kind: ParamFloat, Min: 0.01, Max: 1000, Default: 1, Hidden: true}
+
+// PixelScale is the pixel scale set in p, 1 if it isn't set
+func PixelScale(p Params) float64 {
+	if scale := p.Float(PixelScaleKey); scale > 0 {
+		return scale
+	}
+	return 1
+}
+
+// scalePixels multiplies a size in pixels by the pixel scale in p, rounded and never less than a pixel
+func scalePixels(p Params, pixels int) int {
+	return max(1, int(math.Round(float64(pixels)*PixelScale(p))))
+}
+
+// SourceScaleKey is the parameter holding how many pixels of the original image match one pixel of the image being
+// filtered, which is more than 1 for the shrunk preview. Sizes given in pixels of the original, like a crop or
+// resizing to 800x600, are divided by it so the preview shows what the export will be
+const SourceScaleKey = "control.sourcescale"
+
+// MaxImageSize is the biggest width or height that can be given in pixels, like the size to resize to
+const MaxImageSize = 16384
+
+// sourceScaleParam is added to the parameters of every filter with a size in pixels of the original image
+var sourceScaleParam = FilterParam{Key: SourceScaleKey, Kind: ParamFloat, Min: 0.001, Max: 1000, Default: 1, Hidden: true}
+
+// SourceScale is the source scale set in p, 1 if it isn't set
+func SourceScale(p Params) float64 {
+	if scale := p.Float(SourceScaleKey); scale > 0 {
+		return scale
+	}
+	return 1
+}
+
+// sourcePixels divides a size in pixels of the original image by the source scale in p, rounded
+func sourcePixels(p Params, pixels int) int {
+	return int(math.Round(float64(pixels) / SourceScale(p)))
+}
+
+// FilterRegistry holds every filter that can be used in the pipeline, in the order they're drawn in the UI.
+// To add a new filter implement the Filter interface and add it here
+var FilterRegistry = []Filter{
+	CropFilter{},
+	RotateFilter{},
+	FlipFilter{},
+	ResizeFilter{},
+	GrayscaleFilter{},
+	QuantizingFilter{},
+	PosterizeFilter{},
+	ThresholdFilter{},
+	DitheringFilter{},
+	OrderedDitheringFilter{},
+	PaletteDitheringFilter{},
+	TintFilter{},
+	LevelsFilter{},
+	CurvesFilter{},
+	EqualizeFilter{},
+	CLAHEFilter{},
+	HSLFilter{},
+	BoxBlurFilter{},
+	GaussianBlurFilter{},
+	SharpenFilter{},
+	ConvolutionFilter{},
+	ToneFilter{},
+	LightenDarkenFilter{},
+}
+
+// GetFilter looks up a registered filter by its name
+func GetFilter(name string) (Filter, bool) {
+	for _, f := range FilterRegistry {
+		if f.Name() == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// filters object
+type Filters struct {
+	Enabled map[string]bool
+	Params  Params
+	Order   []string
+	// The colours palette dithering picks from, set from the palette window
+	Palette []rl.Color
+	// The control points of the curves filter, set from the levels and curves window
+	Curves Curves
+}
+
+// NewFilters creates the filter settings with every registered filter at its default values
+func NewFilters() Filters {
+	f := Filters{
+		Enabled: map[string]bool{},
+		Params:  Params{},
+		Order:   make([]string, 0, len(FilterRegistry)),
+	}
+	for _, filter := range FilterRegistry {
+		f.Enabled[filter.Name()] = false
+		for _, p := range filter.Params() {
+			f.Params[p.Key] = p.Default
+		}
+		f.Order = append(f.Order, filter.Name())
+	}
+	// lighten/darken is a no-op at its default so it's always on
+	f.Enabled[LightenDarkenFilter{}.Name()] = true
+	return f
+}
+
+// Clone makes a deep copy so the copy can be changed without affecting the original
+func (f Filters) Clone() Filters {
+	return Filters{
+		Enabled: maps.Clone(f.Enabled),
+		Params:  maps.Clone(f.Params),
+		Order:   slices.Clone(f.Order),
+		Palette: slices.Clone(f.Palette),
+		Curves:  f.Curves.Clone(),
+	}
+}
+
+// Apply runs every enabled filter over img in the order given by Order
+func (f Filters) Apply(img *image.RGBA) {
+	f.ApplyContext(context.Background(), img)
+}
+
+// ApplyContext is Apply but it stops and returns the context's error if ctx is cancelled, slow filters notice
+// part way through and the rest between filters
+func (f Filters) ApplyContext(ctx context.Context, img *image.RGBA) error {
+	s := stageImage{rgba: img}
+	for _, k := range f.Order {
+		// stop early if nobody wants the result any more
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// for each enabled filter, apply it to the image
+		filter, ok := GetFilter(k)
+		if !ok {
+			ErrorLogf("Unknown filter in order: %v", k)
+			continue
+		}
+		if !f.Enabled[k] {
+			continue
+		}
+		t := time.Now()
+		if err := s.apply(ctx, filter, f); err != nil {
+			return err
+		}
+		InfoLogf("%v filter time: %v", k, time.Since(t))
+	}
+	if s.rgba != img {
+		*img = *s.RGBA()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// randomImage makes a reproducible noisy image for comparing filter outputs
+func randomImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	r := rand.New(rand.NewSource(1))
+	r.Read(img.Pix)
+	return img
+}
+
+func TestParallelFilters(t *testing.T) {
+	// Aim: splitting filters across workers should give exactly the same output as running them serially
+	filters := NewFilters()
+	for k := range filters.Enabled {
+		filters.Enabled[k] = true
+	}
+	// dithering isn't split up so leave it out
+	filters.Enabled["control.dithering"] = false
+	filters.Params["control.brightness"] = 0.3
+	filters.Params["control.channeladjustment.green"] = 0.5
+
+	storedWorkers := Workers
+	defer func() { Workers = storedWorkers }()
+
+	serial := randomImage(97, 61)
+	Workers = 1
+	filters.Apply(serial)
+
+	parallel := randomImage(97, 61)
+	Workers = 8
+	filters.Apply(parallel)
+
+	if !bytes.Equal(serial.Pix, parallel.Pix) {
+		t.Error("Parallel output differs from the serial output")
+	}
+}
+
+func TestParallelRows(t *testing.T) {
+	// Aim: every row should be visited exactly once whatever the worker count
+	for _, workers := range []int{1, 3, 16, 200} {
+		storedWorkers := Workers
+		Workers = workers
+		visited := make([]int, 101)
+		ParallelRows(len(visited), func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				visited[y]++
+			}
+		})
+		Workers = storedWorkers
+		for y, v := range visited {
+			if v != 1 {
+				t.Errorf("%d workers: row %d visited %d times", workers, y, v)
+			}
+		}
+	}
+}
+
+func TestParallelRowsContext(t *testing.T) {
+	storedWorkers := Workers
+	defer func() { Workers = storedWorkers }()
+
+	t.Run("Cancelled part way through", func(t *testing.T) {
+		// Aim: once ctx is cancelled no more bands should be started and the context's error is returned
+		for _, workers := range []int{1, 4} {
+			Workers = workers
+			ctx, cancel := context.WithCancel(context.Background())
+			var mu sync.Mutex
+			visited := 0
+			err := ParallelRowsContext(ctx, 1000, func(y0, y1 int) {
+				cancel()
+				mu.Lock()
+				visited += y1 - y0
+				mu.Unlock()
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%d workers: expected the context's error, got %v", workers, err)
+			}
+			if visited >= 1000 {
+				t.Errorf("%d workers: every row was visited after cancelling", workers)
+			}
+		}
+	})
+	t.Run("Slow filters stop", func(t *testing.T) {
+		// Aim: the filters that can take a long time at full resolution should notice a cancel part way through
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		filters := NewFilters()
+		filters.Params["control.resize.width"] = 0.5
+		filters.Params["control.rotate.angle"] = 10
+		for _, k := range []string{"control.gaussianblur", "control.dithering", "control.resize", "control.rotate"} {
+			filter, _ := GetFilter(k)
+			if _, ok := filter.(ContextFilter); !ok {
+				t.Errorf("%v can't be cancelled part way through", k)
+				continue
+			}
+			img := randomImage(16, 16)
+			if err := applyFilter(ctx, filter, img, filters); !errors.Is(err, context.Canceled) {
+				t.Errorf("%v: expected the context's error, got %v", k, err)
+			}
+		}
+	})
+}
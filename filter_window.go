@@ -46,6 +46,12 @@ func (f *FilterOrderWindow) Draw() {
 	}
 	// Put the style back
 	gui.SetStyle(gui.LABEL, gui.TEXT_ALIGNMENT, stashStyle)
+	// Linear light applies to the whole pipeline rather than any one filter
+	linear := gui.CheckBox(rl.NewRectangle(f.Anchor.X+150, f.Anchor.Y+150, 10, 10), Translate(LinearLightKey), LinearLight(state.Filters.Params))
+	state.Filters.Params[LinearLightKey] = 0
+	if linear {
+		state.Filters.Params[LinearLightKey] = 1
+	}
 
 }
 func (f *FilterOrderWindow) Promote() {
@@ -61,7 +67,7 @@ func (f *FilterOrderWindow) Promote() {
 }
 func (f *FilterOrderWindow) Demote() {
 	// if the selected is the bottom dont't do anything
-	if f.Active == FilterCount-1 {
+	if f.Active == int32(len(state.Filters.Order)-1) {
 		DebugLog("Attempted to demote last index")
 	} else {
 		// else swap it with the one above
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image"
+	"slices"
+)
+
+// FlipDirection picks which way the image is mirrored
+type FlipDirection int
+
+const (
+	FlipHorizontal FlipDirection = iota // left and right swap
+	FlipVertical                        // top and bottom swap
+	FlipBoth                            // the same as a half turn
+)
+
+type FlipFilter struct{}
+
+func (FlipFilter) Name() string {
+	return "control.flip"
+}
+func (FlipFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.flip.direction", Kind: ParamChoice, Min: 0, Max: float64(FlipBoth), Default: float64(FlipHorizontal), Options: []string{
+			"control.flip.horizontal",
+			"control.flip.vertical",
+			"control.flip.both",
+		}},
+	}
+}
+func (FlipFilter) Apply(img *image.RGBA, p Params) {
+	Flip(img, FlipDirection(p.Int("control.flip.direction")))
+}
+
+// Flip mirrors img in place
+func Flip(img *image.RGBA, direction FlipDirection) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if direction == FlipVertical || direction == FlipBoth {
+		for y := range h / 2 {
+			top, bottom := img.Pix[y*img.Stride:y*img.Stride+w*4], img.Pix[(h-1-y)*img.Stride:(h-1-y)*img.Stride+w*4]
+			for i := range top {
+				top[i], bottom[i] = bottom[i], top[i]
+			}
+		}
+	}
+	if direction == FlipHorizontal || direction == FlipBoth {
+		ParallelRows(h, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				row := img.Pix[y*img.Stride : y*img.Stride+w*4]
+				// reversing the bytes reverses the pixels and each pixel's channels, so put the channels back
+				slices.Reverse(row)
+				for i := 0; i < len(row); i += 4 {
+					slices.Reverse(row[i : i+4])
+				}
+			}
+		})
+	}
+}
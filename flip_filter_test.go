@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFlipFilter(t *testing.T) {
+	t.Run("Flipping mirrors pixels", func(t *testing.T) {
+		// Aim: each direction moves pixels to the other side without mixing up their channels
+		img := randomImage(5, 3)
+		for direction, at := range map[FlipDirection]func(x, y int) (int, int){
+			FlipHorizontal: func(x, y int) (int, int) { return 4 - x, y },
+			FlipVertical:   func(x, y int) (int, int) { return x, 2 - y },
+			FlipBoth:       func(x, y int) (int, int) { return 4 - x, 2 - y },
+		} {
+			res := ToRGBA(img)
+			Flip(res, direction)
+			for y := range 3 {
+				for x := range 5 {
+					sx, sy := at(x, y)
+					got, expected := res.Pix[y*res.Stride+x*4:y*res.Stride+x*4+4], img.Pix[sy*img.Stride+sx*4:sy*img.Stride+sx*4+4]
+					if !bytes.Equal(got, expected) {
+						t.Fatalf("Expected direction %d to put %v at %d, %d, got %v", direction, expected, x, y, got)
+					}
+				}
+			}
+		}
+	})
+	t.Run("Flipping both is a half turn", func(t *testing.T) {
+		img := randomImage(6, 4)
+		res := ToRGBA(img)
+		Flip(res, FlipBoth)
+		if !bytes.Equal(res.Pix, RotateQuarters(img, 2).Pix) {
+			t.Error("Expected flipping both ways to match a half turn")
+		}
+	})
+}
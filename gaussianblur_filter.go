@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"image"
+)
+
+type GaussianBlurFilter struct{}
+
+func (GaussianBlurFilter) Name() string {
+	return "control.gaussianblur"
+}
+func (GaussianBlurFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.gaussianblur.radius", Kind: ParamInt, Min: 1, Max: 50, Default: 3},
+		{Key: "control.gaussianblur.sigma", Kind: ParamFloat, Min: 0.1, Max: 20, Default: 1.5},
+		{Key: "control.gaussianblur.edgemode", Kind: ParamChoice, Min: 0, Max: float64(len(EdgeModeOptions) - 1), Default: float64(EdgeClamp), Options: EdgeModeOptions},
+		linearLightParam,
+		pixelScaleParam,
+	}
+}
+func (f GaussianBlurFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f GaussianBlurFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	return gaussianBlur(ctx, rgbaPixels(img), p)
+}
+func (GaussianBlurFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	return gaussianBlur(ctx, img.pixels(), f.Params)
+}
+
+func gaussianBlur[S sample](ctx context.Context, px pixels[S], p Params) error {
+	// a gaussian is separable so blur the rows then the columns with the same 1D kernel
+	kernel := GaussianKernel(scalePixels(p, p.Int("control.gaussianblur.radius")), p.Float("control.gaussianblur.sigma")*PixelScale(p))
+	return convolveSeparable(ctx, px, kernel, kernel, EdgeMode(p.Int("control.gaussianblur.edgemode")))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestEdgeIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		i, n     int
+		mode     EdgeMode
+		expected int
+	}{
+		{"inside", 3, 5, EdgeMirror, 3},
+		{"clamp below", -2, 5, EdgeClamp, 0},
+		{"clamp above", 7, 5, EdgeClamp, 4},
+		{"mirror below", -1, 5, EdgeMirror, 1},
+		{"mirror above", 5, 5, EdgeMirror, 3},
+		{"mirror past the whole image", 9, 5, EdgeMirror, 1},
+		{"mirror single pixel", -3, 1, EdgeMirror, 0},
+		{"wrap below", -1, 5, EdgeWrap, 4},
+		{"wrap above", 12, 5, EdgeWrap, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := edgeIndex(tt.i, tt.n, tt.mode); got != tt.expected {
+				t.Errorf("edgeIndex(%d, %d, %d) = %d, expected %d", tt.i, tt.n, tt.mode, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGaussianBlurFilter(t *testing.T) {
+	params := func(radius int, mode EdgeMode) Params {
+		return Params{
+			"control.gaussianblur.radius":   float64(radius),
+			"control.gaussianblur.sigma":    float64(radius) / 2,
+			"control.gaussianblur.edgemode": float64(mode),
+		}
+	}
+	t.Run("Kernel sums to one", func(t *testing.T) {
+		var sum float32
+		for _, w := range GaussianKernel(10, 3) {
+			sum += w
+		}
+		if math.Abs(float64(sum-1)) > 1e-5 {
+			t.Errorf("Kernel sums to %v", sum)
+		}
+	})
+	t.Run("Flat image is unchanged", func(t *testing.T) {
+		// Aim: blurring a single colour should give back the same colour right up to the edges in every mode
+		for _, mode := range []EdgeMode{EdgeClamp, EdgeMirror, EdgeWrap} {
+			img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+			for i := range img.Pix {
+				img.Pix[i] = 200
+			}
+			GaussianBlurFilter{}.Apply(img, params(8, mode))
+			for i, v := range img.Pix {
+				if v != 200 {
+					t.Fatalf("Edge mode %d changed channel %d to %d", mode, i, v)
+				}
+			}
+		}
+	})
+	t.Run("Wrap reaches the opposite edge", func(t *testing.T) {
+		// Aim: with wrapped edges a bright column on the left should bleed into the right edge, but not when clamped
+		bright := func(mode EdgeMode) uint8 {
+			img := image.NewRGBA(image.Rect(0, 0, 16, 4))
+			for y := range 4 {
+				img.Set(0, y, color.RGBA{255, 255, 255, 255})
+			}
+			GaussianBlurFilter{}.Apply(img, params(3, mode))
+			return img.RGBAAt(15, 2).R
+		}
+		if bright(EdgeWrap) == 0 {
+			t.Error("Wrapped blur didn't reach the opposite edge")
+		}
+		if bright(EdgeClamp) != 0 {
+			t.Error("Clamped blur reached the opposite edge")
+		}
+	})
+	t.Run("Large radius", func(t *testing.T) {
+		// Aim: a radius bigger than the image still works
+		img := randomImage(8, 8)
+		GaussianBlurFilter{}.Apply(img, params(50, EdgeMirror))
+	})
+}
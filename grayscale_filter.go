@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// GrayscaleMethod picks how a colour is turned into a single grey value
+type GrayscaleMethod int
+
+const (
+	GrayscaleAverage    GrayscaleMethod = iota // the mean of red, green and blue
+	GrayscaleRec601                            // SD video luma weights
+	GrayscaleRec709                            // HD video luma weights, the same as sRGB's
+	GrayscaleBT2100                            // UHD and HDR video luma weights
+	GrayscaleLightness                         // CIELAB L*, which is closest to how light a colour looks
+	GrayscaleDesaturate                        // halfway between the largest and smallest channel, HSL's lightness
+	GrayscaleRed                               // just the red channel
+	GrayscaleGreen                             // just the green channel
+	GrayscaleBlue                              // just the blue channel
+)
+
+// lumaWeights are the red, green and blue weights of the methods that are weighted sums
+var lumaWeights = map[GrayscaleMethod][3]float64{
+	GrayscaleAverage: {1.0 / 3, 1.0 / 3, 1.0 / 3},
+	GrayscaleRec601:  {0.299, 0.587, 0.114},
+	GrayscaleRec709:  {0.2126, 0.7152, 0.0722},
+	GrayscaleBT2100:  {0.2627, 0.6780, 0.0593},
+}
+
+type GrayscaleFilter struct{}
+
+func (GrayscaleFilter) Name() string {
+	return "control.grayscale"
+}
+func (GrayscaleFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.grayscale.method", Kind: ParamChoice, Min: 0, Max: float64(GrayscaleBlue), Default: float64(GrayscaleAverage), Options: []string{
+			"control.grayscale.average",
+			"control.grayscale.rec601",
+			"control.grayscale.rec709",
+			"control.grayscale.bt2100",
+			"control.grayscale.lightness",
+			"control.grayscale.desaturate",
+			"control.grayscale.red",
+			"control.grayscale.green",
+			"control.grayscale.blue",
+		}},
+		linearLightParam,
+	}
+}
+func (GrayscaleFilter) Apply(img *image.RGBA, p Params) {
+	DebugLog("Grayscale filter applied")
+	method := GrayscaleMethod(p.Int("control.grayscale.method"))
+	linear := LinearLight(p)
+	ParallelPix(img, func(pix []uint8) {
+		// for each pixel
+		for i := 0; i < len(pix); i += 4 {
+			// set the r, g and b to the grey value of r, g and b
+			v := Grey(method, pix[i+0], pix[i+1], pix[i+2], linear)
+			pix[i+0] = v
+			pix[i+1] = v
+			pix[i+2] = v
+		}
+	})
+}
+
+func (GrayscaleFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	method := GrayscaleMethod(f.Params.Int("control.grayscale.method"))
+	return ParallelRowsContext(ctx, img.Rect.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			pix := img.Pix[y*img.Stride : y*img.Stride+img.Rect.Dx()*4]
+			for i := 0; i < len(pix); i += 4 {
+				v := greyLinear(method, pix[i+0], pix[i+1], pix[i+2])
+				pix[i+0] = v
+				pix[i+1] = v
+				pix[i+2] = v
+			}
+		}
+	})
+}
+
+// Grey turns a colour into a single grey value with method. In linear light the weighted sums give true luminance
+// instead of luma, lightness is always worked out from linear light and the single channel methods don't do any
+// arithmetic so they're the same either way
+func Grey(method GrayscaleMethod, r, g, b uint8, linear bool) uint8 {
+	switch method {
+	case GrayscaleLightness:
+		// the grey with the same L*, which has no a* or b*
+		grey, _, _ := FromLab([3]float64{ToLab(r, g, b)[0], 0, 0})
+		return grey
+	case GrayscaleDesaturate:
+		if linear {
+			return FromLinear((max(ToLinear(r), ToLinear(g), ToLinear(b)) + min(ToLinear(r), ToLinear(g), ToLinear(b))) / 2)
+		}
+		return uint8((int(max(r, g, b)) + int(min(r, g, b)) + 1) / 2)
+	case GrayscaleRed:
+		return r
+	case GrayscaleGreen:
+		return g
+	case GrayscaleBlue:
+		return b
+	}
+	if method == GrayscaleAverage && !linear {
+		// the plain average is done with integers, which is how it's always been done
+		return uint8((int(r) + int(g) + int(b)) / 3)
+	}
+	weights, ok := lumaWeights[method]
+	if !ok {
+		weights = lumaWeights[GrayscaleAverage]
+	}
+	if linear {
+		return FromLinear(weights[0]*ToLinear(r) + weights[1]*ToLinear(g) + weights[2]*ToLinear(b))
+	}
+	return uint8(math.Round(Clamp(weights[0]*float64(r)+weights[1]*float64(g)+weights[2]*float64(b), 0, 255)))
+}
+
+// greyLinear is Grey for a colour in linear light on the 0 to 255 scale
+func greyLinear(method GrayscaleMethod, r, g, b float32) float32 {
+	switch method {
+	case GrayscaleLightness:
+		// L* only depends on luminance, so the grey with the same L* is the grey with the same luminance
+		method = GrayscaleRec709
+	case GrayscaleDesaturate:
+		return (max(r, g, b) + min(r, g, b)) / 2
+	case GrayscaleRed:
+		return r
+	case GrayscaleGreen:
+		return g
+	case GrayscaleBlue:
+		return b
+	}
+	weights, ok := lumaWeights[method]
+	if !ok {
+		weights = lumaWeights[GrayscaleAverage]
+	}
+	return float32(weights[0])*r + float32(weights[1])*g + float32(weights[2])*b
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestGrayscaleMethods(t *testing.T) {
+	t.Run("Methods", func(t *testing.T) {
+		// Aim: each method should weigh pure green the way it's defined to
+		cases := map[GrayscaleMethod]uint8{
+			GrayscaleAverage:    85,
+			GrayscaleRec601:     150,
+			GrayscaleRec709:     182,
+			GrayscaleBT2100:     173,
+			GrayscaleLightness:  220,
+			GrayscaleDesaturate: 128,
+			GrayscaleRed:        0,
+			GrayscaleGreen:      255,
+			GrayscaleBlue:       0,
+		}
+		for method, expected := range cases {
+			if got := Grey(method, 0, 255, 0, false); got != expected {
+				t.Errorf("Method %d: expected %d, got %d", method, expected, got)
+			}
+		}
+	})
+	t.Run("Black, white and greys stay the same", func(t *testing.T) {
+		// Aim: a colour that's already grey shouldn't change whichever method or light is used
+		for method := GrayscaleAverage; method <= GrayscaleBlue; method++ {
+			for _, linear := range []bool{false, true} {
+				for _, v := range []uint8{0, 60, 128, 255} {
+					if got := Grey(method, v, v, v, linear); got != v && (got < v-1 || got > v+1) {
+						t.Errorf("Method %d linear %v: expected %d to stay the same, got %d", method, linear, v, got)
+					}
+				}
+			}
+		}
+	})
+	t.Run("Luminance in linear light", func(t *testing.T) {
+		// Aim: Rec. 709 weights on linear light are the definition of luminance, so pure green is 71.5% of the light
+		if got, expected := Grey(GrayscaleRec709, 0, 255, 0, true), FromLinear(0.7152); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+	t.Run("Method parameter", func(t *testing.T) {
+		img := randomImage(1, 1)
+		img.Pix[0], img.Pix[1], img.Pix[2] = 10, 20, 200
+		p := NewFilters().Params
+		p["control.grayscale.method"] = float64(GrayscaleBlue)
+		GrayscaleFilter{}.Apply(img, p)
+		if img.Pix[0] != 200 || img.Pix[1] != 200 || img.Pix[2] != 200 {
+			t.Errorf("Expected the blue channel everywhere, got %v", img.Pix[:3])
+		}
+	})
+}
@@ -24,4 +24,10 @@ func (p *HelpWindow) Draw() {
 	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+70, 300, 40), "O - "+Translate("window.help.order"))
 	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+90, 300, 40), "S - "+Translate("window.help.save"))
 	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+110, 300, 40), ", - "+Translate("window.help.settings"))
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+130, 300, 40), "U - "+Translate("window.help.history"))
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+150, 300, 40), "P - "+Translate("window.help.presets"))
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+170, 300, 40), "K - "+Translate("window.help.convolution"))
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+190, 300, 40), "L - "+Translate("window.help.curves"))
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+210, 300, 40), "Ctrl+Z - "+Translate("window.help.undo"))
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+230, 300, 40), "Ctrl+Shift+Z - "+Translate("window.help.redo"))
 }
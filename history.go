@@ -0,0 +1,102 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/cnf/structhash"
+)
+
+// MaxHistoryDepth is how many filter states are kept before the oldest ones are forgotten
+const MaxHistoryDepth = 100
+
+// HistoryEntry is a snapshot of the filters after a single user interaction
+type HistoryEntry struct {
+	Filters Filters
+	// Translation key for what changed, shown in the history window
+	Label string
+}
+
+// History is an undo/redo stack of filter snapshots
+type History struct {
+	Entries []HistoryEntry
+	// Index of the entry that matches the current filters
+	Current int
+}
+
+// Record adds a snapshot of f if it differs from the current entry, anything that could have been redone is dropped
+func (h *History) Record(f Filters) {
+	if len(h.Entries) > 0 {
+		current := h.Entries[h.Current].Filters
+		if filtersEqual(current, f) {
+			return
+		}
+		h.Entries = append(h.Entries[:h.Current+1], HistoryEntry{Filters: f.Clone(), Label: changedKey(current, f)})
+	} else {
+		h.Entries = append(h.Entries, HistoryEntry{Filters: f.Clone(), Label: "window.history.initial"})
+	}
+	// forget the oldest entries once it gets too deep
+	if len(h.Entries) > MaxHistoryDepth {
+		h.Entries = slices.Delete(h.Entries, 0, len(h.Entries)-MaxHistoryDepth)
+	}
+	h.Current = len(h.Entries) - 1
+}
+
+// Undo steps back one entry and returns a copy of its filters, ok is false if there's nothing to undo
+func (h *History) Undo() (f Filters, ok bool) {
+	return h.JumpTo(h.Current - 1)
+}
+
+// Redo steps forward one entry and returns a copy of its filters, ok is false if there's nothing to redo
+func (h *History) Redo() (f Filters, ok bool) {
+	return h.JumpTo(h.Current + 1)
+}
+
+// JumpTo moves to any entry and returns a copy of its filters, the entries after it are kept so they can be redone
+func (h *History) JumpTo(i int) (f Filters, ok bool) {
+	if i < 0 || i >= len(h.Entries) {
+		return f, false
+	}
+	h.Current = i
+	return h.Entries[i].Filters.Clone(), true
+}
+
+func filtersEqual(a, b Filters) bool {
+	aHash, _ := structhash.Hash(a, 1)
+	bHash, _ := structhash.Hash(b, 1)
+	return aHash == bHash
+}
+
+// changedKey finds the translation key of the first thing that differs between two filter states
+func changedKey(prev, next Filters) string {
+	if !slices.Equal(prev.Order, next.Order) {
+		return "window.history.order"
+	}
+	// linear light is shared by several filters so it's named on its own rather than after the first of them
+	if prev.Params[LinearLightKey] != next.Params[LinearLightKey] {
+		return LinearLightKey
+	}
+	// the palette and curves aren't parameters but they only belong to one filter each
+	if !slices.Equal(prev.Palette, next.Palette) {
+		return PaletteDitheringFilter{}.Name()
+	}
+	for channel := range prev.Curves {
+		if !slices.Equal(prev.Curves[channel], next.Curves[channel]) {
+			return CurvesFilter{}.Name()
+		}
+	}
+	for _, filter := range FilterRegistry {
+		if prev.Enabled[filter.Name()] != next.Enabled[filter.Name()] {
+			return filter.Name()
+		}
+		for _, p := range filter.Params() {
+			if prev.Params[p.Key] != next.Params[p.Key] {
+				// hidden parameters don't have their own names so use the filter's
+				if p.Hidden {
+					return filter.Name()
+				}
+				return p.Key
+			}
+		}
+	}
+	return "window.history.change"
+}
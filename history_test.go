@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestHistory(t *testing.T) {
+	t.Run("Undo and redo", func(t *testing.T) {
+		var h History
+		f := NewFilters()
+		h.Record(f)
+		f.Enabled["control.grayscale"] = true
+		h.Record(f)
+
+		undone, ok := h.Undo()
+		if !ok || undone.Enabled["control.grayscale"] {
+			t.Error("Undo didn't go back to grayscale being off")
+		}
+		redone, ok := h.Redo()
+		if !ok || !redone.Enabled["control.grayscale"] {
+			t.Error("Redo didn't go forward to grayscale being on")
+		}
+		if _, ok := h.Redo(); ok {
+			t.Error("Redo past the end of the history succeeded")
+		}
+	})
+	t.Run("Unchanged filters aren't recorded", func(t *testing.T) {
+		// Aim: recording every mouse release shouldn't fill the history with duplicates
+		var h History
+		f := NewFilters()
+		h.Record(f)
+		h.Record(f)
+		h.Record(f.Clone())
+		if len(h.Entries) != 1 {
+			t.Errorf("Expected 1 entry, got %d", len(h.Entries))
+		}
+	})
+	t.Run("Recording drops redo entries", func(t *testing.T) {
+		var h History
+		f := NewFilters()
+		h.Record(f)
+		f.Params["control.brightness"] = 0.5
+		h.Record(f)
+		h.Undo()
+		f.Params["control.brightness"] = -0.5
+		h.Record(f)
+		if len(h.Entries) != 2 || h.Entries[1].Filters.Params["control.brightness"] != -0.5 {
+			t.Errorf("Expected the redo entry to be replaced, got %+v", h.Entries)
+		}
+		if h.Entries[1].Label != "control.brightness" {
+			t.Errorf("Expected label control.brightness, got %v", h.Entries[1].Label)
+		}
+	})
+	t.Run("Bounded depth", func(t *testing.T) {
+		var h History
+		f := NewFilters()
+		for i := range MaxHistoryDepth + 20 {
+			f.Params["control.brightness"] = float64(i) / 1000
+			h.Record(f)
+		}
+		if len(h.Entries) != MaxHistoryDepth || h.Current != MaxHistoryDepth-1 {
+			t.Errorf("Expected %d entries, got %d with current %d", MaxHistoryDepth, len(h.Entries), h.Current)
+		}
+	})
+	t.Run("Snapshots aren't shared", func(t *testing.T) {
+		// Aim: changing the live filters shouldn't change what's stored in the history
+		var h History
+		f := NewFilters()
+		h.Record(f)
+		f.Enabled["control.boxblur"] = true
+		if h.Entries[0].Filters.Enabled["control.boxblur"] {
+			t.Error("History entry changed along with the live filters")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	gui "github.com/gen2brain/raylib-go/raygui"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type HistoryWindow struct {
+	Showing        bool
+	Anchor         rl.Vector2
+	ScrollIndex    int32
+	InteractedWith time.Time
+}
+
+func (h *HistoryWindow) getRect() rl.Rectangle {
+	return rl.NewRectangle(h.Anchor.X, h.Anchor.Y, 300, 300)
+}
+
+// Draw the history window
+func (h *HistoryWindow) Draw() {
+	h.Showing = !gui.WindowBox(h.getRect(), Translate("window.history.title"))
+	// Undo button
+	if gui.Button(rl.NewRectangle(h.Anchor.X+10, h.Anchor.Y+30, 135, 30), Translate("window.history.undo")) {
+		state.Undo()
+	}
+	// Redo button
+	if gui.Button(rl.NewRectangle(h.Anchor.X+155, h.Anchor.Y+30, 135, 30), Translate("window.history.redo")) {
+		state.Redo()
+	}
+	// Draw the list of every change, selecting one jumps back to it
+	labels := make([]string, len(state.History.Entries))
+	for i, entry := range state.History.Entries {
+		labels[i] = fmt.Sprintf("%d. %s", i+1, Translate(entry.Label))
+	}
+	active := gui.ListView(
+		rl.NewRectangle(h.Anchor.X+10, h.Anchor.Y+70, 280, 220),
+		strings.Join(labels, ";"),
+		&h.ScrollIndex,
+		int32(state.History.Current),
+	)
+	if active >= 0 && int(active) != state.History.Current {
+		state.JumpToHistory(int(active))
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// ColourModel picks which cylinder the hue/saturation stage works in
+type ColourModel int
+
+const (
+	ModelHSL ColourModel = iota
+	ModelHSV
+)
+
+// HueRange picks which colours the hue/saturation stage changes
+type HueRange int
+
+const (
+	HueAll HueRange = iota
+	HueReds
+	HueYellows
+	HueGreens
+	HueCyans
+	HueBlues
+	HueMagentas
+)
+
+// Hue rotation, saturation, vibrance and lightness, optionally only for one range of hues
+type HSLFilter struct{}
+
+func (HSLFilter) Name() string {
+	return "control.hsl"
+}
+func (HSLFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.hsl.model", Kind: ParamChoice, Min: 0, Max: float64(ModelHSV), Default: float64(ModelHSL), Options: []string{"control.hsl.model.hsl", "control.hsl.model.hsv"}},
+		{Key: "control.hsl.range", Kind: ParamChoice, Min: 0, Max: float64(HueMagentas), Default: float64(HueAll), Options: []string{
+			"control.hsl.range.all",
+			"control.hsl.range.reds",
+			"control.hsl.range.yellows",
+			"control.hsl.range.greens",
+			"control.hsl.range.cyans",
+			"control.hsl.range.blues",
+			"control.hsl.range.magentas",
+		}},
+		{Key: "control.hsl.hue", Kind: ParamFloat, Min: -180, Max: 180, Default: 0},
+		{Key: "control.hsl.saturation", Kind: ParamFloat, Min: -1, Max: 1, Default: 0},
+		{Key: "control.hsl.vibrance", Kind: ParamFloat, Min: -1, Max: 1, Default: 0},
+		{Key: "control.hsl.lightness", Kind: ParamFloat, Min: -1, Max: 1, Default: 0},
+	}
+}
+func (HSLFilter) Apply(img *image.RGBA, p Params) {
+	model := ColourModel(p.Int("control.hsl.model"))
+	hueRange := HueRange(p.Int("control.hsl.range"))
+	hue := p.Float("control.hsl.hue")
+	saturation := p.Float("control.hsl.saturation")
+	vibrance := p.Float("control.hsl.vibrance")
+	lightness := p.Float("control.hsl.lightness")
+	ParallelPix(img, func(pix []uint8) {
+		for i := 0; i < len(pix); i += 4 {
+			r, g, b := float64(pix[i+0])/255, float64(pix[i+1])/255, float64(pix[i+2])/255
+			var h, s, l float64
+			if model == ModelHSV {
+				h, s, l = RGBToHSV(r, g, b)
+			} else {
+				h, s, l = RGBToHSL(r, g, b)
+			}
+			weight := hueWeight(hueRange, h, s)
+			if weight == 0 {
+				continue
+			}
+			h = math.Mod(h+hue*weight+360, 360)
+			// saturation and vibrance scale the saturation so greys stay grey, vibrance boosts the duller colours most
+			s = Clamp(s*(1+saturation*weight), 0, 1)
+			s = Clamp(s*(1+vibrance*weight*(1-s)), 0, 1)
+			// lightness moves towards black or white
+			if amount := lightness * weight; amount < 0 {
+				l *= 1 + amount
+			} else {
+				l += (1 - l) * amount
+			}
+			if model == ModelHSV {
+				r, g, b = HSVToRGB(h, s, l)
+			} else {
+				r, g, b = HSLToRGB(h, s, l)
+			}
+			pix[i+0] = uint8(math.Round(Clamp(r, 0, 1) * 255))
+			pix[i+1] = uint8(math.Round(Clamp(g, 0, 1) * 255))
+			pix[i+2] = uint8(math.Round(Clamp(b, 0, 1) * 255))
+		}
+	})
+}
+
+// hueWeight is how much a colour with hue h and saturation s is in hueRange, from 0 to 1. Each range is fully
+// changed within 15 degrees of its centre and fades out by 45 degrees, so neighbouring ranges overlap smoothly.
+// Greys have no real hue so they're never part of a range
+func hueWeight(hueRange HueRange, h, s float64) float64 {
+	if hueRange == HueAll {
+		return 1
+	}
+	if s == 0 {
+		return 0
+	}
+	centre := float64(hueRange-HueReds) * 60
+	distance := math.Abs(math.Mod(h-centre+540, 360) - 180)
+	return Clamp((45-distance)/30, 0, 1)
+}
+
+// hueToRGB is the red, green and blue of a fully saturated colour at hue h in degrees
+func hueToRGB(h float64) (r, g, b float64) {
+	h = math.Mod(h, 360) / 60
+	channel := func(n float64) float64 {
+		k := math.Mod(n+h, 6)
+		return 1 - Clamp(math.Min(k, 4-k), 0, 1)
+	}
+	return channel(5), channel(3), channel(1)
+}
+
+// hueDegrees is the hue of a colour given its largest channel and its chroma, the difference between its largest and
+// smallest channels
+func hueDegrees(r, g, b, largest, chroma float64) float64 {
+	if chroma == 0 {
+		return 0
+	}
+	var h float64
+	switch largest {
+	case r:
+		h = math.Mod((g-b)/chroma, 6)
+	case g:
+		h = (b-r)/chroma + 2
+	default:
+		h = (r-g)/chroma + 4
+	}
+	return math.Mod(h*60+360, 360)
+}
+
+// RGBToHSL converts a colour with channels from 0 to 1 to hue in degrees, saturation and lightness
+func RGBToHSL(r, g, b float64) (h, s, l float64) {
+	largest, smallest := max(r, g, b), min(r, g, b)
+	chroma := largest - smallest
+	l = (largest + smallest) / 2
+	if chroma > 0 {
+		s = chroma / (1 - math.Abs(2*l-1))
+	}
+	return hueDegrees(r, g, b, largest, chroma), s, l
+}
+
+// HSLToRGB converts hue in degrees, saturation and lightness back to channels from 0 to 1
+func HSLToRGB(h, s, l float64) (r, g, b float64) {
+	chroma := (1 - math.Abs(2*l-1)) * s
+	r, g, b = hueToRGB(h)
+	m := l - chroma/2
+	return r*chroma + m, g*chroma + m, b*chroma + m
+}
+
+// RGBToHSV converts a colour with channels from 0 to 1 to hue in degrees, saturation and value
+func RGBToHSV(r, g, b float64) (h, s, v float64) {
+	largest, smallest := max(r, g, b), min(r, g, b)
+	chroma := largest - smallest
+	if largest > 0 {
+		s = chroma / largest
+	}
+	return hueDegrees(r, g, b, largest, chroma), s, largest
+}
+
+// HSVToRGB converts hue in degrees, saturation and value back to channels from 0 to 1
+func HSVToRGB(h, s, v float64) (r, g, b float64) {
+	chroma := v * s
+	r, g, b = hueToRGB(h)
+	m := v - chroma
+	return r*chroma + m, g*chroma + m, b*chroma + m
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestHSLFilter(t *testing.T) {
+	params := func(values map[string]float64) Params {
+		p := NewFilters().Params
+		for k, v := range values {
+			p[k] = v
+		}
+		return p
+	}
+	apply := func(c color.RGBA, p Params) color.RGBA {
+		img := randomImage(1, 1)
+		img.SetRGBA(0, 0, c)
+		HSLFilter{}.Apply(img, p)
+		return img.RGBAAt(0, 0)
+	}
+
+	t.Run("Conversions round trip", func(t *testing.T) {
+		// Aim: going to HSL or HSV and back should give the colour that went in
+		for _, c := range []color.RGBA{{0, 0, 0, 255}, {255, 255, 255, 255}, {200, 30, 30, 255}, {12, 200, 99, 255}, {90, 90, 250, 255}, {128, 128, 128, 255}} {
+			r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+			for name, back := range map[string][3]float64{
+				"HSL": func() [3]float64 { r, g, b := HSLToRGB(RGBToHSL(r, g, b)); return [3]float64{r, g, b} }(),
+				"HSV": func() [3]float64 { r, g, b := HSVToRGB(RGBToHSV(r, g, b)); return [3]float64{r, g, b} }(),
+			} {
+				if math.Abs(back[0]-r)+math.Abs(back[1]-g)+math.Abs(back[2]-b) > 1e-9 {
+					t.Errorf("%v through %v came back as %v", c, name, back)
+				}
+			}
+		}
+	})
+	t.Run("Default settings leave the image alone", func(t *testing.T) {
+		for _, model := range []ColourModel{ModelHSL, ModelHSV} {
+			img := randomImage(16, 16)
+			expected := ToRGBA(img)
+			HSLFilter{}.Apply(img, params(map[string]float64{"control.hsl.model": float64(model)}))
+			if !bytes.Equal(img.Pix, expected.Pix) {
+				t.Errorf("Model %d changed the image", model)
+			}
+		}
+	})
+	t.Run("Hue rotation", func(t *testing.T) {
+		// Aim: rotating by 120 degrees should turn red into green and -120 should turn it into blue
+		red := color.RGBA{255, 0, 0, 255}
+		if c := apply(red, params(map[string]float64{"control.hsl.hue": 120})); c != (color.RGBA{0, 255, 0, 255}) {
+			t.Errorf("Expected green, got %v", c)
+		}
+		if c := apply(red, params(map[string]float64{"control.hsl.hue": -120})); c != (color.RGBA{0, 0, 255, 255}) {
+			t.Errorf("Expected blue, got %v", c)
+		}
+	})
+	t.Run("Saturation and vibrance", func(t *testing.T) {
+		// Aim: -1 saturation makes a colour grey, and vibrance boosts dull colours more than vivid ones but never greys
+		if c := apply(color.RGBA{200, 50, 50, 255}, params(map[string]float64{"control.hsl.saturation": -1})); c.R != c.G || c.G != c.B {
+			t.Errorf("Expected grey, got %v", c)
+		}
+		vibrance := params(map[string]float64{"control.hsl.vibrance": 1})
+		if c := apply(color.RGBA{128, 128, 128, 255}, vibrance); c != (color.RGBA{128, 128, 128, 255}) {
+			t.Errorf("Expected grey to stay grey, got %v", c)
+		}
+		_, dullBefore, _ := RGBToHSL(140/255.0, 110/255.0, 110/255.0)
+		dull := apply(color.RGBA{140, 110, 110, 255}, vibrance)
+		_, dullAfter, _ := RGBToHSL(float64(dull.R)/255, float64(dull.G)/255, float64(dull.B)/255)
+		_, vividBefore, _ := RGBToHSL(230/255.0, 30/255.0, 30/255.0)
+		vivid := apply(color.RGBA{230, 30, 30, 255}, vibrance)
+		_, vividAfter, _ := RGBToHSL(float64(vivid.R)/255, float64(vivid.G)/255, float64(vivid.B)/255)
+		if dullAfter/dullBefore <= vividAfter/vividBefore {
+			t.Errorf("Expected the dull colour's saturation to grow more, got %.2fx and %.2fx", dullAfter/dullBefore, vividAfter/vividBefore)
+		}
+	})
+	t.Run("Lightness", func(t *testing.T) {
+		// Aim: -1 and 1 lightness should give black and white
+		c := color.RGBA{30, 140, 200, 255}
+		if got := apply(c, params(map[string]float64{"control.hsl.lightness": -1})); got != (color.RGBA{0, 0, 0, 255}) {
+			t.Errorf("Expected black, got %v", got)
+		}
+		if got := apply(c, params(map[string]float64{"control.hsl.lightness": 1})); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("Expected white, got %v", got)
+		}
+	})
+	t.Run("Hue range", func(t *testing.T) {
+		// Aim: only reds should change when reds are targeted, and greys never should
+		p := params(map[string]float64{"control.hsl.range": float64(HueReds), "control.hsl.saturation": -1})
+		if c := apply(color.RGBA{220, 40, 40, 255}, p); c.R != c.G {
+			t.Errorf("Expected red to become grey, got %v", c)
+		}
+		for _, c := range []color.RGBA{{40, 220, 40, 255}, {40, 40, 220, 255}, {100, 100, 100, 255}} {
+			if got := apply(c, p); got != c {
+				t.Errorf("Expected %v to be left alone, got %v", c, got)
+			}
+		}
+		// orange is between the red and yellow ranges so it should only be partly desaturated
+		orange := apply(color.RGBA{255, 128, 0, 255}, p)
+		if orange.R == orange.B || orange == (color.RGBA{255, 128, 0, 255}) {
+			t.Errorf("Expected orange to be partly desaturated, got %v", orange)
+		}
+	})
+	t.Run("Alpha is left alone", func(t *testing.T) {
+		if c := apply(color.RGBA{10, 20, 30, 40}, params(map[string]float64{"control.hsl.hue": 90})); c.A != 40 {
+			t.Errorf("Expected alpha 40, got %d", c.A)
+		}
+	})
+}
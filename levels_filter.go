@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// LevelsKey is the parameter holding a channel's setting, which is "black", "white" or "gamma"
+func LevelsKey(channel ToneChannel, setting string) string {
+	return fmt.Sprintf("control.levels.%s.%s", ToneChannels[channel], setting)
+}
+
+// SetLevels sets a channel's black point, white point and gamma
+func SetLevels(p Params, channel ToneChannel, black, white int, gamma float64) {
+	p[LevelsKey(channel, "black")] = float64(black)
+	p[LevelsKey(channel, "white")] = float64(white)
+	p[LevelsKey(channel, "gamma")] = gamma
+}
+
+// Black point, white point and gamma for every channel, edited from the sidebar or the levels and curves window
+type LevelsFilter struct{}
+
+func (LevelsFilter) Name() string {
+	return "control.levels"
+}
+func (LevelsFilter) Params() []FilterParam {
+	params := make([]FilterParam, 0, len(ToneChannels)*3)
+	for c := range ToneChannels {
+		params = append(params,
+			FilterParam{Key: LevelsKey(ToneChannel(c), "black"), Kind: ParamInt, Min: 0, Max: 254, Default: 0},
+			FilterParam{Key: LevelsKey(ToneChannel(c), "white"), Kind: ParamInt, Min: 1, Max: 255, Default: 255},
+			FilterParam{Key: LevelsKey(ToneChannel(c), "gamma"), Kind: ParamFloat, Min: 0.1, Max: 10, Default: 1},
+		)
+	}
+	return params
+}
+func (LevelsFilter) Apply(img *image.RGBA, p Params) {
+	r, g, b := toneLUTs(func(c ToneChannel) LUT {
+		return LevelsLUT(p.Int(LevelsKey(c, "black")), p.Int(LevelsKey(c, "white")), p.Float(LevelsKey(c, "gamma")))
+	})
+	ApplyLUTs(img, r, g, b)
+}
+
+// LevelsLUT stretches black to white over the whole range, then bends the middle by gamma.
+// A gamma above 1 brightens the mid tones and below 1 darkens them
+func LevelsLUT(black, white int, gamma float64) LUT {
+	// the white point has to be above the black point for there to be a range to stretch
+	white = max(white, black+1)
+	var l LUT
+	for i := range l {
+		v := Clamp(float64(i-black)/float64(white-black), 0, 1)
+		l[i] = uint8(math.Round(math.Pow(v, 1/gamma) * 255))
+	}
+	return l
+}
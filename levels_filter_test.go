@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLevelsFilter(t *testing.T) {
+	t.Run("Default levels leave the image alone", func(t *testing.T) {
+		img := randomImage(16, 16)
+		expected := ToRGBA(img)
+		LevelsFilter{}.Apply(img, NewFilters().Params)
+		if !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Default levels changed the image")
+		}
+	})
+	t.Run("Black and white points stretch the range", func(t *testing.T) {
+		// Aim: everything at or below the black point is 0, at or above the white point is 255, and halfway between is halfway
+		l := LevelsLUT(50, 150, 1)
+		for v, expected := range map[int]uint8{0: 0, 50: 0, 100: 128, 150: 255, 200: 255} {
+			if l[v] != expected {
+				t.Errorf("Expected %d to become %d, got %d", v, expected, l[v])
+			}
+		}
+	})
+	t.Run("Gamma bends the mid tones", func(t *testing.T) {
+		// Aim: a gamma above 1 brightens the middle and below 1 darkens it, without moving black or white
+		bright, dark := LevelsLUT(0, 255, 2), LevelsLUT(0, 255, 0.5)
+		if bright[128] <= 128 || dark[128] >= 128 {
+			t.Errorf("Expected gamma 2 to brighten and 0.5 to darken 128, got %d and %d", bright[128], dark[128])
+		}
+		if bright[0] != 0 || bright[255] != 255 || dark[0] != 0 || dark[255] != 255 {
+			t.Error("Gamma moved black or white")
+		}
+	})
+	t.Run("White point at or below the black point", func(t *testing.T) {
+		// Aim: a crossed over range should act like a threshold instead of dividing by zero
+		l := LevelsLUT(100, 100, 1)
+		if l[100] != 0 || l[101] != 255 {
+			t.Errorf("Expected a threshold at 100, got %d and %d", l[100], l[101])
+		}
+	})
+	t.Run("Channels and the master levels", func(t *testing.T) {
+		// Aim: a channel's levels only change that channel, and the master levels are applied after them to every channel
+		p := NewFilters().Params
+		SetLevels(p, ToneRed, 0, 128, 1)
+		SetLevels(p, ToneAll, 0, 255, 2)
+		img := randomImage(1, 1)
+		img.Pix[0], img.Pix[1], img.Pix[2] = 64, 64, 64
+		LevelsFilter{}.Apply(img, p)
+		master := LevelsLUT(0, 255, 2)
+		if expected := master[128]; img.Pix[0] != expected {
+			t.Errorf("Expected red to be %d, got %d", expected, img.Pix[0])
+		}
+		if expected := master[64]; img.Pix[1] != expected || img.Pix[2] != expected {
+			t.Errorf("Expected green and blue to be %d, got %d and %d", expected, img.Pix[1], img.Pix[2])
+		}
+	})
+}
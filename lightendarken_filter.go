@@ -0,0 +1,27 @@
+package main
+
+import "image"
+
+type LightenDarkenFilter struct{}
+
+func (LightenDarkenFilter) Name() string {
+	return "control.lightendarken"
+}
+func (LightenDarkenFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.brightness", Kind: ParamFloat, Min: -1, Max: 1, Default: 0},
+	}
+}
+func (LightenDarkenFilter) Apply(img *image.RGBA, p Params) {
+	// +1 makes everything white
+	// -1 makes everything black
+	// 0 does nothing
+	factor := p.Float("control.brightness") + 1
+	ParallelPix(img, func(pix []uint8) {
+		for i := 0; i < len(pix); i += 4 {
+			pix[i+0] = uint8(Clamp(float64(pix[i+0])*factor, 0, 255))
+			pix[i+1] = uint8(Clamp(float64(pix[i+1])*factor, 0, 255))
+			pix[i+2] = uint8(Clamp(float64(pix[i+2])*factor, 0, 255))
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+	"slices"
+)
+
+// LinearLightKey is the pipeline wide setting for blending pixels in linear light rather than on their sRGB values,
+// which keeps blurs from going muddy and makes luminance right. It isn't owned by any one filter, every filter that
+// honours it lists it as a hidden parameter so the filter's cache key includes it
+const LinearLightKey = "control.linearlight"
+
+// linearLightParam is the parameter filters that honour LinearLightKey list
+var linearLightParam = FilterParam{Key: LinearLightKey, Kind: ParamInt, Min: 0, Max: 1, Default: 0, Hidden: true}
+
+// LinearLight is whether filters should do their arithmetic in linear light
+func LinearLight(p Params) bool {
+	return p.Int(LinearLightKey) == 1
+}
+
+// srgbToLinear is a lookup table from an sRGB channel to linear light between 0 and 1
+var srgbToLinear = func() [256]float64 {
+	var table [256]float64
+	for i := range table {
+		table[i] = DecodeSRGB(float64(i) / 255)
+	}
+	return table
+}()
+
+// DecodeSRGB undoes the sRGB curve of a channel value between 0 and 1, giving linear light between 0 and 1
+func DecodeSRGB(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearSteps is how finely linearToSRGB is tabulated. It's fine enough that the darkest sRGB values, which are
+// closest together in linear light, still come back exactly
+const linearSteps = 1 << 16
+
+// EncodeSRGB applies the sRGB curve to linear light between 0 and 1, giving the channel value between 0 and 1
+func EncodeSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// linearToSRGB is a lookup table from linear light in linearSteps steps to an sRGB channel
+var linearToSRGB = func() []uint8 {
+	table := make([]uint8, linearSteps+1)
+	for i := range table {
+		table[i] = uint8(math.Round(EncodeSRGB(float64(i)/linearSteps) * 255))
+	}
+	return table
+}()
+
+// ToLinear converts an sRGB channel to linear light between 0 and 1
+func ToLinear(v uint8) float64 {
+	return srgbToLinear[v]
+}
+
+// FromLinear converts linear light to the nearest sRGB channel value, values outside 0 to 1 are clamped
+func FromLinear(v float64) uint8 {
+	return linearToSRGB[int(Clamp(v, 0, 1)*linearSteps+0.5)]
+}
+
+// linearScale is ToLinear on the same 0 to 255 scale as the channel, for filters that work on that scale
+func linearScale(v uint8) float32 {
+	return float32(srgbToLinear[v] * 255)
+}
+
+// fromLinearScale is FromLinear for values on the 0 to 255 scale
+func fromLinearScale(v float32) uint8 {
+	return FromLinear(float64(v) / 255)
+}
+
+// channelScale gives how a filter working on the 0 to 255 scale should read and write channels, converting to and
+// from linear light when linear is set
+func channelScale(linear bool) (read func(uint8) float32, write func(float32) uint8) {
+	if linear {
+		return linearScale, fromLinearScale
+	}
+	return func(v uint8) float32 { return float32(v) }, clampUint8
+}
+
+// LinearImage is an image in linear light. When linear light is on the pipeline converts to one before a run of
+// LinearFilter stages and back after it, so the stages in between don't round to 8 bits. The channels are floats on
+// the same 0 to 255 scale as *image.RGBA
+type LinearImage struct {
+	Pix    []float32
+	Stride int
+	Rect   image.Rectangle
+}
+
+// NewLinearImage makes a transparent black width by height image
+func NewLinearImage(width, height int) *LinearImage {
+	return &LinearImage{Pix: make([]float32, width*height*4), Stride: width * 4, Rect: image.Rect(0, 0, width, height)}
+}
+
+// ToLinearImage converts img to linear light, alpha is copied as it is
+func ToLinearImage(img *image.RGBA) *LinearImage {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	res := NewLinearImage(w, h)
+	ParallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range w {
+				i, j := y*img.Stride+x*4, y*res.Stride+x*4
+				res.Pix[j+0] = linearScale(img.Pix[i+0])
+				res.Pix[j+1] = linearScale(img.Pix[i+1])
+				res.Pix[j+2] = linearScale(img.Pix[i+2])
+				res.Pix[j+3] = float32(img.Pix[i+3])
+			}
+		}
+	})
+	return res
+}
+
+// RGBA converts l back to 8-bit sRGB
+func (l *LinearImage) RGBA() *image.RGBA {
+	w, h := l.Rect.Dx(), l.Rect.Dy()
+	res := image.NewRGBA(image.Rect(0, 0, w, h))
+	ParallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range w {
+				i, j := y*res.Stride+x*4, y*l.Stride+x*4
+				res.Pix[i+0] = fromLinearScale(l.Pix[j+0])
+				res.Pix[i+1] = fromLinearScale(l.Pix[j+1])
+				res.Pix[i+2] = fromLinearScale(l.Pix[j+2])
+				res.Pix[i+3] = clampUint8(l.Pix[j+3])
+			}
+		}
+	})
+	return res
+}
+
+// Clone makes a copy of l that can be changed without affecting it
+func (l *LinearImage) Clone() *LinearImage {
+	res := *l
+	res.Pix = slices.Clone(l.Pix)
+	return &res
+}
+
+// clampChannel keeps a LinearImage channel between 0 and 255, it isn't rounded
+func clampChannel(v float32) float32 {
+	return min(max(v, 0), 255)
+}
+
+// sample is a channel of an 8-bit sRGB image or of a LinearImage
+type sample interface{ uint8 | float32 }
+
+// pixels are the channels of an *image.RGBA or a LinearImage, four to a pixel, so the filters that blend pixels
+// together can be written once for both
+type pixels[S sample] struct {
+	Pix    []S
+	Stride int
+	Width  int
+	Height int
+	// Linear is set for a LinearImage, whose colours are in linear light
+	Linear bool
+	// write turns a value on the 0 to 255 scale back into a channel, rounding it for 8 bits
+	write func(float32) S
+}
+
+func rgbaPixels(img *image.RGBA) pixels[uint8] {
+	return pixels[uint8]{Pix: img.Pix, Stride: img.Stride, Width: img.Rect.Dx(), Height: img.Rect.Dy(), write: clampUint8}
+}
+
+func (l *LinearImage) pixels() pixels[float32] {
+	return pixels[float32]{Pix: l.Pix, Stride: l.Stride, Width: l.Rect.Dx(), Height: l.Rect.Dy(), Linear: true, write: clampChannel}
+}
+
+// resized is an empty width by height image of the same kind as p
+func (p pixels[S]) resized(width, height int) pixels[S] {
+	p.Pix, p.Stride, p.Width, p.Height = make([]S, width*height*4), width*4, width, height
+	return p
+}
+
+// clone is a copy of p's channels that can be changed without affecting them
+func (p pixels[S]) clone() pixels[S] {
+	p.Pix = slices.Clone(p.Pix)
+	return p
+}
+
+// rgba wraps the channels of an 8-bit p as an image
+func (p pixels[S]) rgba() *image.RGBA {
+	return &image.RGBA{Pix: any(p.Pix).([]uint8), Stride: p.Stride, Rect: image.Rect(0, 0, p.Width, p.Height)}
+}
+
+// linearImage wraps the channels of a linear p as an image
+func (p pixels[S]) linearImage() *LinearImage {
+	return &LinearImage{Pix: any(p.Pix).([]float32), Stride: p.Stride, Rect: image.Rect(0, 0, p.Width, p.Height)}
+}
+
+// sRGB is the 8-bit sRGB value of a channel of p
+func (p pixels[S]) sRGB(v S) uint8 {
+	if p.Linear {
+		return fromLinearScale(float32(v))
+	}
+	return uint8(v)
+}
+
+// fromSRGB is the channel of p holding an 8-bit sRGB value
+func (p pixels[S]) fromSRGB(v uint8) S {
+	if p.Linear {
+		return S(linearScale(v))
+	}
+	return S(v)
+}
+
+// applyLinear applies filter to img in linear light on its own, for when a LinearFilter with linear light on is
+// applied outside the pipeline
+func applyLinear(ctx context.Context, filter LinearFilter, img *image.RGBA, f Filters) error {
+	l := ToLinearImage(img)
+	if err := filter.ApplyLinear(ctx, l, f); err != nil {
+		return err
+	}
+	*img = *l.RGBA()
+	return nil
+}
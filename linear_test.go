@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestLinearLight(t *testing.T) {
+	t.Run("Conversions round trip", func(t *testing.T) {
+		// Aim: every sRGB value should come back exactly after going to linear light, including the darkest ones
+		for v := range 256 {
+			if got := FromLinear(ToLinear(uint8(v))); got != uint8(v) {
+				t.Errorf("%d came back as %d", v, got)
+			}
+		}
+		if FromLinear(-1) != 0 || FromLinear(2) != 255 {
+			t.Error("Expected values outside 0 to 1 to be clamped")
+		}
+	})
+
+	// alternating black and white columns, which blur to a flat grey
+	stripes := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+		for y := range 32 {
+			for x := range 32 {
+				v := uint8(0)
+				if x%2 == 0 {
+					v = 255
+				}
+				img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+			}
+		}
+		return img
+	}
+	linear := func(on bool) Params {
+		p := NewFilters().Params
+		if on {
+			p[LinearLightKey] = 1
+		}
+		return p
+	}
+	// each filter with the parameters it needs to average neighbouring columns
+	blurs := map[string]func(p Params) Filter{
+		"box blur":      func(p Params) Filter { return BoxBlurFilter{} },
+		"gaussian blur": func(p Params) Filter { return GaussianBlurFilter{} },
+		"resize": func(p Params) Filter {
+			p["control.resize.width"] = 0.5
+			p["control.resize.resample"] = float64(ResampleBilinear)
+			return ResizeFilter{}
+		},
+		"convolution": func(p Params) Filter {
+			// the pixel and the one to its right
+			p[KernelKey(false, MaxKernelSize/2, MaxKernelSize/2+1)] = 1
+			p["control.convolution.divisor"] = 2
+			return ConvolutionFilter{}
+		},
+	}
+	for name, setup := range blurs {
+		t.Run("Blurring with "+name, func(t *testing.T) {
+			// Aim: black and white average to half the light, which is sRGB 188 rather than 128
+			for on, expected := range map[bool]uint8{false: 128, true: 188} {
+				img := stripes()
+				p := linear(on)
+				setup(p).Apply(img, p)
+				if got := img.RGBAAt(img.Rect.Dx()/2, 16); got.R < expected-8 || got.R > expected+8 || got.A != 255 {
+					t.Errorf("Linear light %v: expected about %d with alpha left alone, got %v", on, expected, got)
+				}
+			}
+		})
+	}
+	t.Run("Dithering keeps the light the same", func(t *testing.T) {
+		// Aim: dithering a flat grey in linear light should keep the average light the same, while dithering in sRGB
+		// keeps the average sRGB value the same instead
+		for _, on := range []bool{false, true} {
+			p := linear(on)
+			p["control.dithering.buckets"] = 2
+			img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+			for i := range img.Pix {
+				img.Pix[i] = 100
+			}
+			DitheringFilter{}.Apply(img, p)
+			var light, value float64
+			for i := 0; i < len(img.Pix); i += 4 {
+				light += ToLinear(img.Pix[i])
+				value += float64(img.Pix[i])
+			}
+			light, value = light/(64*64), value/(64*64)
+			if on && math.Abs(light-ToLinear(100)) > 0.005 {
+				t.Errorf("Expected the average light to be %.3f, got %.3f", ToLinear(100), light)
+			}
+			if !on && math.Abs(value-100) > 2 {
+				t.Errorf("Expected the average value to be 100, got %.1f", value)
+			}
+		}
+	})
+	t.Run("Runs of stages stay in linear light", func(t *testing.T) {
+		// Aim: a run of linear stages should be handed the same unrounded image, and only go back to 8 bits for a
+		// stage that works in sRGB
+		f := NewFilters()
+		f.Params = linear(true)
+		s := stageImage{rgba: stripes()}
+		for _, filter := range []Filter{GaussianBlurFilter{}, BoxBlurFilter{}} {
+			if err := s.apply(context.Background(), filter, f); err != nil {
+				t.Fatal(err)
+			}
+			if s.linear == nil || s.rgba != nil {
+				t.Fatalf("Expected %v to leave the image in linear light", filter.Name())
+			}
+		}
+		if err := s.apply(context.Background(), LevelsFilter{}, f); err != nil {
+			t.Fatal(err)
+		}
+		if s.linear != nil || s.rgba == nil {
+			t.Error("Expected levels to be given an 8-bit image")
+		}
+	})
+	t.Run("Pipeline matches applying directly", func(t *testing.T) {
+		// Aim: the cache keeping linear images between stages should give the same result as applying the filters
+		// one at a time, which rounds to 8 bits after each
+		src := randomImage(24, 24)
+		f := NewFilters()
+		f.Params = linear(true)
+		f.Params["control.rotate.angle"] = 10
+		for _, k := range []string{"control.rotate", "control.gaussianblur", "control.sharpen", "control.levels", "control.grayscale"} {
+			f.Enabled[k] = true
+		}
+		var c PipelineCache
+		cached, err := c.Run(context.Background(), src, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		direct := ToRGBA(src)
+		for _, k := range f.Order {
+			if filter, _ := GetFilter(k); f.Enabled[k] {
+				filter.Apply(direct, f.Params)
+			}
+		}
+		if cached.Rect != direct.Rect {
+			t.Fatalf("Expected %v, got %v", direct.Rect, cached.Rect)
+		}
+		for i := range cached.Pix {
+			if d := int(cached.Pix[i]) - int(direct.Pix[i]); d < -2 || d > 2 {
+				t.Fatalf("Channel %d: expected about %d, got %d", i, direct.Pix[i], cached.Pix[i])
+			}
+		}
+	})
+	t.Run("Cache key includes linear light", func(t *testing.T) {
+		// Aim: switching linear light has to re-run the stages that use it
+		if stageKey(BoxBlurFilter{}, Filters{Params: linear(false)}) == stageKey(BoxBlurFilter{}, Filters{Params: linear(true)}) {
+			t.Error("Box blur's cache key didn't change with linear light")
+		}
+	})
+}
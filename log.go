@@ -29,7 +29,7 @@ func InfoLogf(format string, args ...any) {
 	if Level < Info {
 		return
 	}
-	color.New(color.FgHiBlack).Printf(fmt.Sprintf("%s\n", format), args)
+	color.New(color.FgHiBlack).Printf(fmt.Sprintf("%s\n", format), args...)
 }
 
 func DebugLog(format string) {
@@ -42,7 +42,7 @@ func DebugLogf(format string, args ...any) {
 	if Level < Debug {
 		return
 	}
-	color.New(color.FgCyan).Printf(fmt.Sprintf("%s\n", format), args)
+	color.New(color.FgCyan).Printf(fmt.Sprintf("%s\n", format), args...)
 }
 
 func ErrorLog(format string) {
@@ -55,7 +55,7 @@ func ErrorLogf(format string, args ...any) {
 	if Level < Error {
 		return
 	}
-	color.New(color.FgRed).Printf(fmt.Sprintf("%s\n", format), args)
+	color.New(color.FgRed).Printf(fmt.Sprintf("%s\n", format), args...)
 }
 
 func FatalLog(format string) {
@@ -63,6 +63,6 @@ func FatalLog(format string) {
 	os.Exit(1)
 }
 func FatalLogf(format string, args ...any) {
-	color.New(color.BgRed).Add(color.FgBlack).Add(color.Bold).Printf(fmt.Sprintf("%s\n", format), args)
+	color.New(color.BgRed).Add(color.FgBlack).Add(color.Bold).Printf(fmt.Sprintf("%s\n", format), args...)
 	os.Exit(1)
 }
@@ -0,0 +1,57 @@
+package main
+
+import "image"
+
+// LUT is a lookup table from every value a channel can have to what it should become
+type LUT [256]uint8
+
+// IdentityLUT leaves every value as it is
+func IdentityLUT() LUT {
+	var l LUT
+	for i := range l {
+		l[i] = uint8(i)
+	}
+	return l
+}
+
+// Then gives a table that does l followed by next
+func (l LUT) Then(next LUT) LUT {
+	var out LUT
+	for i, v := range l {
+		out[i] = next[v]
+	}
+	return out
+}
+
+// ApplyLUTs maps the red, green and blue of every pixel through their tables, alpha is left alone
+func ApplyLUTs(img *image.RGBA, r, g, b LUT) {
+	ParallelPix(img, func(pix []uint8) {
+		for i := 0; i < len(pix); i += 4 {
+			pix[i+0] = r[pix[i+0]]
+			pix[i+1] = g[pix[i+1]]
+			pix[i+2] = b[pix[i+2]]
+		}
+	})
+}
+
+// ToneChannel is which channels a levels or curves adjustment is for
+type ToneChannel int
+
+const (
+	ToneAll ToneChannel = iota // applied to every channel after its own adjustment
+	ToneRed
+	ToneGreen
+	ToneBlue
+)
+
+// ToneChannels are the key segments of each channel's parameters, in ToneChannel order
+var ToneChannels = []string{"all", "red", "green", "blue"}
+
+// ToneChannelNames are the translation keys of each channel, in ToneChannel order
+var ToneChannelNames = []string{"colour.all", "colour.red", "colour.green", "colour.blue"}
+
+// toneLUTs builds the table for each of red, green and blue by following the channel's own table with ToneAll's
+func toneLUTs(channel func(ToneChannel) LUT) (r, g, b LUT) {
+	all := channel(ToneAll)
+	return channel(ToneRed).Then(all), channel(ToneGreen).Then(all), channel(ToneBlue).Then(all)
+}
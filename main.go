@@ -5,6 +5,7 @@ package main
 import (
 	"fmt"
 	"math"
+	"os"
 	"slices"
 	"strings"
 	"time"
@@ -20,6 +21,11 @@ import (
 var state State
 
 func main() {
+	// run headless if there's a command, this has to happen before the window is opened
+	if len(os.Args) > 1 && !IsProjectFile(os.Args[1]) {
+		os.Exit(RunCommand(os.Args[1:]))
+	}
+
 	rl.InitWindow(800, 600, "")
 	defer rl.CloseWindow()
 	rl.SetTargetFPS(60 * 2)
@@ -31,6 +37,8 @@ func main() {
 	
 	// get the hash of the current filter configuration
 	oldFiltersHash, _ := structhash.Hash(state.Filters, 1)
+	// set when the filters have changed but haven't been recorded in the history yet
+	historyPending := false
 
 	// start the help window
 	state.HelpWindow.Showing = true
@@ -39,6 +47,13 @@ func main() {
 	// set the window title now the translations have loaded
 	rl.SetWindowTitle(Translate("main.title"))
 
+	// open a project if one was given on the command line
+	if len(os.Args) > 1 {
+		if err := state.LoadProject(os.Args[1]); err != nil {
+			ErrorLogf("Couldn't load project: %v", err.Error())
+		}
+	}
+
 	for !rl.WindowShouldClose() {
 		rl.BeginDrawing() // Begin drawing the current frame
 		rl.ClearBackground(rl.GetColor(uint(gui.GetStyle(0, 19)))) // set the background colour to match the theme
@@ -52,8 +67,7 @@ func main() {
 			// handle drag and drop file loading on the window
 			if rl.IsFileDropped() {
 				list := rl.LoadDroppedFiles()
-				state.ImagePath = list[0]
-				state.LoadImageFile(list[0])
+				state.LoadDroppedFile(list[0])
 				rl.UnloadDroppedFiles()
 			}
 			// shortcircuit the rest of the loop
@@ -65,103 +79,23 @@ func main() {
 		// apply all the filters
 		// only reload the texture if the filters have changed because it's quite slow
 		oldFiltersHash, _ = structhash.Hash(state.Filters, 1)
-		rl.DrawTexture(state.CurrentTexture, 0, 0, rl.White)
+		state.DrawPreview()
 
 		//if rl.IsKeyPressed(rl.KeyG) {
 		//	state.GenerateNoiseImage(500, 500)
 		//}
+		// files dropped once an image is open replace it, or change the palette if they're palette files
+		if rl.IsFileDropped() {
+			list := rl.LoadDroppedFiles()
+			state.LoadDroppedFile(list[0])
+			rl.UnloadDroppedFiles()
+		}
 		// DRAW UI
-		// TODO: seperate out into functions
-		// Grayscale enabled checkbox
-		state.Filters.IsGrayscaleEnabled = gui.CheckBox(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 10, 10, 10),
-			Translate("control.grayscale"),
-			state.Filters.IsGrayscaleEnabled,
-		)
-		// Dithering enabled checkbox
-		state.Filters.IsDitheringEnabled = gui.CheckBox( // gabagool
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 40, 10, 10),
-			Translate("control.dithering"),
-			state.Filters.IsDitheringEnabled,
-		)
-		// Dithering strength slider
-		state.Filters.DitheringQuantizationBuckets = uint8(math.Trunc(float64(gui.Slider(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 55, 100, 10),
-			"2", "15", float32(state.Filters.DitheringQuantizationBuckets), 2.0, 15.0))))
-
-		// Quantization enabled checkbox
-		state.Filters.IsQuantizingEnabled = gui.CheckBox( // gabagool
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 85, 10, 10),
-			Translate("control.quantizing"),
-			state.Filters.IsQuantizingEnabled,
-		)
-		// Quantization strength slider
-		state.Filters.QuantizingBands = uint8(math.Trunc(float64(gui.Slider(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 100, 100, 10),
-			fmt.Sprintf("%s: %d   3", Translate("control.quantizationbands"), state.Filters.QuantizingBands),
-			"255",
-			float32(state.Filters.QuantizingBands),
-			3.0,
-			16.0,
-		))))
-		// Tint enabled checkbox
-		state.Filters.ChannelAdjustmentEnabled = gui.CheckBox(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 130, 10, 10),
-			Translate("control.channeladjustment"),
-			state.Filters.ChannelAdjustmentEnabled,
-		)
-		// Tint slider (Red)
-		state.Filters.ChannelAdjustment[0] = gui.Slider(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 145, 100, 10),
-			fmt.Sprintf("%s 0.0", Translate("colour.red")),
-			"1.0",
-			state.Filters.ChannelAdjustment[0],
-			0.0,
-			1.0,
-		)
-		// Tint slider (Green)
-		state.Filters.ChannelAdjustment[1] = gui.Slider(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 160, 100, 10),
-			fmt.Sprintf("%s 0.0", Translate("colour.green")),
-			"1.0",
-			state.Filters.ChannelAdjustment[1],
-			0.0,
-			1.0,
-		)
-		// Tint slider (Blue)
-		state.Filters.ChannelAdjustment[2] = gui.Slider(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 175, 100, 10),
-			fmt.Sprintf("%s 0.0", Translate("colour.blue")),
-			"1.0",
-			state.Filters.ChannelAdjustment[2],
-			0.0,
-			1.0,
-		)
-
-		// Blur enabled checkbox
-		state.Filters.IsBoxBlurEnabled = gui.CheckBox(rl.NewRectangle(float32(rl.GetScreenWidth()-200), 205, 10, 10), Translate("control.boxblur"), state.Filters.IsBoxBlurEnabled)
-		// Blur strength slider
-		state.Filters.BoxBlurIterations = int(gui.Slider(
-			rl.NewRectangle(float32(rl.GetScreenWidth()-200), 225, 100, 10),
-			fmt.Sprintf("%s: %d 1 ", Translate("control.boxblur.iterations"), state.Filters.BoxBlurIterations),
-			"10",
-			float32(state.Filters.BoxBlurIterations),
-			1.0,
-			10.0,
-		))
-
-		gui.Label(rl.NewRectangle(float32(rl.GetScreenWidth()-200), 245, 100, 10), Translate("control.brightness"))
-		// Brightness slider
-		state.Filters.LightenDarken = float64(gui.Slider(rl.Rectangle{
-			X:      float32(rl.GetScreenWidth() - 200),
-			Y:      265,
-			Width:  100,
-			Height: 10,
-		}, "-1.0", "1.0", float32(state.Filters.LightenDarken), -1.0, 1.0))
+		DrawFilterControls()
 
 		mousePos := rl.GetMousePosition()
 		// handle window toggling
-		if rl.IsKeyPressed(rl.KeyO) {
+		if HotkeyPressed(rl.KeyO) {
 			DebugLog("Toggling filter order window")
 			state.FilterWindow.Anchor = rl.Vector2{
 				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.FilterWindow.getRect().Width))),
@@ -170,7 +104,7 @@ func main() {
 			state.FilterWindow.Showing = !state.FilterWindow.Showing
 			state.FilterWindow.InteractedWith = time.Now()
 		}
-		if rl.IsKeyPressed(rl.KeyC) {
+		if HotkeyPressed(rl.KeyC) {
 			DebugLog("Toggling palette window")
 			state.PaletteWindow.Anchor = rl.Vector2{
 				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.PaletteWindow.getRect().Width))),
@@ -179,7 +113,7 @@ func main() {
 			state.PaletteWindow.Showing = !state.PaletteWindow.Showing
 			state.PaletteWindow.InteractedWith = time.Now()
 		}
-		if rl.IsKeyPressed(rl.KeyH) {
+		if HotkeyPressed(rl.KeyH) {
 			DebugLog("Toggling help window")
 			state.HelpWindow.Anchor = rl.Vector2{
 				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.HelpWindow.getRect().Width))),
@@ -188,7 +122,7 @@ func main() {
 			state.HelpWindow.Showing = !state.HelpWindow.Showing
 			state.HelpWindow.InteractedWith = time.Now()
 		}
-		if rl.IsKeyPressed(rl.KeyS) {
+		if HotkeyPressed(rl.KeyS) {
 			DebugLog("Toggling save & load window")
 			state.SaveLoadWindow.Anchor = rl.Vector2{
 				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.SaveLoadWindow.getRect().Width))),
@@ -197,7 +131,7 @@ func main() {
 			state.SaveLoadWindow.Showing = !state.SaveLoadWindow.Showing
 			state.SaveLoadWindow.InteractedWith = time.Now()
 		}
-		if rl.IsKeyPressed(rl.KeyComma) {
+		if HotkeyPressed(rl.KeyComma) {
 			DebugLog("Toggling settings window")
 			state.SettingsWindow.Anchor = rl.Vector2{
 				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.SettingsWindow.getRect().Width))),
@@ -206,13 +140,57 @@ func main() {
 			state.SettingsWindow.Showing = !state.SettingsWindow.Showing
 			state.SettingsWindow.InteractedWith = time.Now()
 		}
+		if HotkeyPressed(rl.KeyU) {
+			DebugLog("Toggling history window")
+			state.HistoryWindow.Anchor = rl.Vector2{
+				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.HistoryWindow.getRect().Width))),
+				Y: min(mousePos.Y, float32(rl.GetScreenHeight()-int(state.HistoryWindow.getRect().Height))),
+			}
+			state.HistoryWindow.Showing = !state.HistoryWindow.Showing
+			state.HistoryWindow.InteractedWith = time.Now()
+		}
+		if HotkeyPressed(rl.KeyP) {
+			DebugLog("Toggling presets window")
+			state.PresetsWindow.Anchor = rl.Vector2{
+				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.PresetsWindow.getRect().Width))),
+				Y: min(mousePos.Y, float32(rl.GetScreenHeight()-int(state.PresetsWindow.getRect().Height))),
+			}
+			state.PresetsWindow.Showing = !state.PresetsWindow.Showing
+			state.PresetsWindow.InteractedWith = time.Now()
+		}
+		if HotkeyPressed(rl.KeyK) {
+			DebugLog("Toggling convolution window")
+			state.ConvolutionWindow.Anchor = rl.Vector2{
+				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.ConvolutionWindow.getRect().Width))),
+				Y: min(mousePos.Y, float32(rl.GetScreenHeight()-int(state.ConvolutionWindow.getRect().Height))),
+			}
+			state.ConvolutionWindow.Showing = !state.ConvolutionWindow.Showing
+			state.ConvolutionWindow.InteractedWith = time.Now()
+		}
+		if HotkeyPressed(rl.KeyL) {
+			DebugLog("Toggling levels and curves window")
+			state.CurvesWindow.Anchor = rl.Vector2{
+				X: min(mousePos.X, float32(rl.GetScreenWidth()-int(state.CurvesWindow.getRect().Width))),
+				Y: min(mousePos.Y, float32(rl.GetScreenHeight()-int(state.CurvesWindow.getRect().Height))),
+			}
+			state.CurvesWindow.Showing = !state.CurvesWindow.Showing
+			state.CurvesWindow.InteractedWith = time.Now()
+		}
+		// Ctrl+Z undoes and Ctrl+Shift+Z redoes
+		if (rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)) && HotkeyPressed(rl.KeyZ) {
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				state.Redo()
+			} else {
+				state.Undo()
+			}
+		}
 		// close the window when Q is pressed
-		if rl.IsKeyPressed(rl.KeyQ) {
+		if HotkeyPressed(rl.KeyQ) {
 			state.Close()
 		}
 
 		// Draw the windows in the order they've been opened
-		times := []int64{state.HelpWindow.InteractedWith.Unix(), state.PaletteWindow.InteractedWith.Unix(), state.FilterWindow.InteractedWith.Unix(), state.SaveLoadWindow.InteractedWith.Unix(), state.SettingsWindow.InteractedWith.Unix()}
+		times := []int64{state.HelpWindow.InteractedWith.Unix(), state.PaletteWindow.InteractedWith.Unix(), state.FilterWindow.InteractedWith.Unix(), state.SaveLoadWindow.InteractedWith.Unix(), state.SettingsWindow.InteractedWith.Unix(), state.HistoryWindow.InteractedWith.Unix(), state.PresetsWindow.InteractedWith.Unix(), state.ConvolutionWindow.InteractedWith.Unix(), state.CurvesWindow.InteractedWith.Unix()}
 		slices.Sort(times)
 		for _, t := range times {
 			switch t {
@@ -236,14 +214,44 @@ func main() {
 				if state.SettingsWindow.Showing {
 					state.SettingsWindow.Draw()
 				}
+			case state.HistoryWindow.InteractedWith.Unix():
+				if state.HistoryWindow.Showing {
+					state.HistoryWindow.Draw()
+				}
+			case state.PresetsWindow.InteractedWith.Unix():
+				if state.PresetsWindow.Showing {
+					state.PresetsWindow.Draw()
+				}
+			case state.ConvolutionWindow.InteractedWith.Unix():
+				if state.ConvolutionWindow.Showing {
+					state.ConvolutionWindow.Draw()
+				}
+			case state.CurvesWindow.InteractedWith.Unix():
+				if state.CurvesWindow.Showing {
+					state.CurvesWindow.Draw()
+				}
 			}
 		}
 
-		// Check if any filters have been changed, if so reload the image and apply filters
+		// Save any changed settings, waiting until the font size slider has been let go
+		if !rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			state.SaveSettingsIfChanged()
+		}
+
+		// Check if any filters have been changed, if so start applying them in the background
 		newFiltersHash, _ := structhash.Hash(state.Filters, 1)
 		if strings.Compare(newFiltersHash, oldFiltersHash) != 0 {
 			state.RefreshImage()
+			historyPending = true
+		}
+		// Record any change to the filters whether it came from the mouse, a hotkey or a text box, waiting until
+		// the mouse is let go so dragging a slider is a single undo step
+		if historyPending && !rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			state.History.Record(state.Filters)
+			historyPending = false
 		}
+		// show the new image once it's finished rendering
+		state.ReceiveRender()
 		// TODO: remove this in the final version
 		rl.DrawFPS(10, 10)
 		// Finish draw call batching
@@ -253,4 +261,78 @@ func main() {
 	state.Close()
 }
 
-// BUG: QuantizeValue when BucketCount = 8 and 255
+// HotkeyPressed is rl.IsKeyPressed but ignores keys while text is being typed into a text box
+func HotkeyPressed(key int32) bool {
+	return !state.IsEditingText() && rl.IsKeyPressed(key)
+}
+
+// sidebarWidth is how much room is left at the side of the preview for the filter controls
+const sidebarWidth = 400
+
+// DrawFilterControls draws a checkbox for every registered filter with a slider for each of its parameters, in a
+// panel down the side that scrolls when they don't all fit
+func DrawFilterControls() {
+	bounds := rl.NewRectangle(float32(rl.GetScreenWidth()-sidebarWidth), 0, sidebarWidth, float32(rl.GetScreenHeight()))
+	// work out how tall the controls are so the panel knows how far it can scroll
+	height := float32(10)
+	for _, filter := range FilterRegistry {
+		height += 30
+		for _, p := range filter.Params() {
+			if !p.Hidden {
+				height += 15
+			}
+		}
+	}
+	var view rl.Rectangle
+	gui.ScrollPanel(bounds, "", rl.NewRectangle(0, 0, sidebarWidth-20, height), &state.SidebarScroll, &view)
+	rl.BeginScissorMode(int32(view.X), int32(view.Y), int32(view.Width), int32(view.Height))
+	defer rl.EndScissorMode()
+	// raygui still reacts to controls outside the scissor rectangle, so anything not entirely in view isn't drawn
+	inView := func(y float32) bool {
+		return y >= view.Y && y+10 <= view.Y+view.Height
+	}
+
+	// the sliders' labels go to their left
+	x := bounds.X + sidebarWidth/2 + state.SidebarScroll.X
+	y := bounds.Y + 10 + state.SidebarScroll.Y
+	for _, filter := range FilterRegistry {
+		// Enabled checkbox
+		if inView(y) {
+			state.Filters.Enabled[filter.Name()] = gui.CheckBox(
+				rl.NewRectangle(x, y, 10, 10),
+				Translate(filter.Name()),
+				state.Filters.Enabled[filter.Name()],
+			)
+		}
+		y += 15
+		// Parameter sliders
+		for _, p := range filter.Params() {
+			if p.Hidden {
+				continue
+			}
+			if !inView(y) {
+				y += 15
+				continue
+			}
+			value := state.Filters.Params[p.Key]
+			label := fmt.Sprintf("%s: %.2f", Translate(p.Key), value)
+			switch p.Kind {
+			case ParamInt:
+				label = fmt.Sprintf("%s: %d", Translate(p.Key), int(value))
+			case ParamChoice:
+				label = fmt.Sprintf("%s: %s", Translate(p.Key), Translate(p.Options[int(value)]))
+			}
+			value = float64(gui.Slider(rl.NewRectangle(x, y, 100, 10), label, "", float32(value), float32(p.Min), float32(p.Max)))
+			switch p.Kind {
+			case ParamInt:
+				value = math.Trunc(value)
+			case ParamChoice:
+				// round so every choice gets an equal share of the slider
+				value = math.Round(value)
+			}
+			state.Filters.Params[p.Key] = value
+			y += 15
+		}
+		y += 15
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"image"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// DitherPattern picks the threshold map used for ordered dithering
+type DitherPattern int
+
+const (
+	Bayer2 DitherPattern = iota
+	Bayer4
+	Bayer8
+	BlueNoise
+)
+
+// BlueNoiseSize is the width and height of the blue noise texture, it tiles without seams
+const BlueNoiseSize = 64
+
+// thresholdMap holds a threshold between 0 and 1 for each pixel of a size x size tile
+type thresholdMap struct {
+	size       int
+	thresholds []float32
+}
+
+func (t thresholdMap) at(x, y int) float32 {
+	return t.thresholds[(y%t.size)*t.size+x%t.size]
+}
+
+// rankedThresholds turns a tile of ranks from 0 to n-1 into thresholds spread evenly between 0 and 1
+func rankedThresholds(size int, ranks []int) thresholdMap {
+	res := thresholdMap{size: size, thresholds: make([]float32, len(ranks))}
+	for i, rank := range ranks {
+		res.thresholds[i] = (float32(rank) + 0.5) / float32(len(ranks))
+	}
+	return res
+}
+
+// BayerMatrix builds the size x size Bayer index matrix, size has to be a power of 2
+func BayerMatrix(size int) []int {
+	// each step makes a matrix twice the size out of 4 copies of the last one
+	m := []int{0}
+	for n := 1; n < size; n *= 2 {
+		next := make([]int, 4*n*n)
+		for y := range n {
+			for x := range n {
+				v := 4 * m[y*n+x]
+				next[y*2*n+x] = v
+				next[y*2*n+x+n] = v + 2
+				next[(y+n)*2*n+x] = v + 3
+				next[(y+n)*2*n+x+n] = v + 1
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+var (
+	blueNoiseOnce sync.Once
+	blueNoise     []int
+)
+
+// BlueNoiseRanks makes a tileable BlueNoiseSize x BlueNoiseSize blue noise texture the first time it's
+// needed, using the void and cluster method. The seed is fixed so the pattern is the same every time
+func BlueNoiseRanks() []int {
+	blueNoiseOnce.Do(func() {
+		blueNoise = voidAndCluster(BlueNoiseSize, 1.5, rand.New(rand.NewPCG(1, 2)))
+	})
+	return blueNoise
+}
+
+// voidAndCluster ranks every pixel of a size x size tile so that the pixels below any rank are spread
+// out as evenly as possible
+func voidAndCluster(size int, sigma float64, rng *rand.Rand) []int {
+	n := size * size
+	// how much each pixel pushes on the others depending on how far away it is, wrapping at the edges
+	kernel := make([]float64, n)
+	for dy := range size {
+		for dx := range size {
+			x := float64(min(dx, size-dx))
+			y := float64(min(dy, size-dy))
+			kernel[dy*size+dx] = math.Exp(-(x*x + y*y) / (2 * sigma * sigma))
+		}
+	}
+	// energy is how crowded each pixel is by the pixels that are set
+	energy := make([]float64, n)
+	set := make([]bool, n)
+	update := func(p int, sign float64) {
+		set[p] = sign > 0
+		px, py := p%size, p/size
+		for q := range n {
+			dx := (q%size - px + size) % size
+			dy := (q/size - py + size) % size
+			energy[q] += sign * kernel[dy*size+dx]
+		}
+	}
+	// the tightest cluster is the most crowded set pixel, the largest void the least crowded unset one
+	tightestCluster := func() int {
+		best := -1
+		for p := range n {
+			if set[p] && (best < 0 || energy[p] > energy[best]) {
+				best = p
+			}
+		}
+		return best
+	}
+	largestVoid := func() int {
+		best := -1
+		for p := range n {
+			if !set[p] && (best < 0 || energy[p] < energy[best]) {
+				best = p
+			}
+		}
+		return best
+	}
+
+	// start with a tenth of the pixels set at random, then move clusters into voids until it settles
+	initial := n / 10
+	for _, p := range rng.Perm(n)[:initial] {
+		update(p, 1)
+	}
+	for range n {
+		cluster := tightestCluster()
+		update(cluster, -1)
+		void := largestVoid()
+		update(void, 1)
+		if void == cluster {
+			break
+		}
+	}
+	start := append([]bool(nil), set...)
+	startEnergy := append([]float64(nil), energy...)
+
+	ranks := make([]int, n)
+	// rank the starting pixels by taking out the tightest clusters first
+	for rank := initial - 1; rank >= 0; rank-- {
+		p := tightestCluster()
+		update(p, -1)
+		ranks[p] = rank
+	}
+	// then rank the rest by filling in the largest voids
+	copy(set, start)
+	copy(energy, startEnergy)
+	for rank := initial; rank < n; rank++ {
+		p := largestVoid()
+		update(p, 1)
+		ranks[p] = rank
+	}
+	return ranks
+}
+
+// Ordered dithering compares each pixel against a repeating threshold map instead of spreading the error,
+// so every pixel can be done at once and flat areas get a steady pattern
+type OrderedDitheringFilter struct{}
+
+func (OrderedDitheringFilter) Name() string {
+	return "control.ordereddithering"
+}
+func (OrderedDitheringFilter) Params() []FilterParam {
+	// uses the same number of buckets as error diffusion dithering so switching between them keeps the levels
+	buckets := DitheringFilter{}.Params()[0]
+	buckets.Hidden = true
+	return []FilterParam{
+		{Key: "control.ordereddithering.pattern", Kind: ParamChoice, Min: 0, Max: float64(BlueNoise), Default: float64(Bayer4), Options: []string{
+			"control.ordereddithering.bayer2",
+			"control.ordereddithering.bayer4",
+			"control.ordereddithering.bayer8",
+			"control.ordereddithering.bluenoise",
+		}},
+		buckets,
+	}
+}
+
+func (OrderedDitheringFilter) Apply(img *image.RGBA, p Params) {
+	buckets := uint8(p.Int("control.dithering.buckets"))
+	var thresholds thresholdMap
+	switch pattern := DitherPattern(p.Int("control.ordereddithering.pattern")); pattern {
+	case BlueNoise:
+		thresholds = rankedThresholds(BlueNoiseSize, BlueNoiseRanks())
+	default:
+		size := 2 << pattern
+		thresholds = rankedThresholds(size, BayerMatrix(size))
+	}
+	// Quantize rounds down so pushing each value up by part of a bucket rounds it up that often
+	step := float32(BucketSize(buckets))
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	ParallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				offset := thresholds.at(x, y) * step
+				i := y*img.Stride + x*4
+				for c := range 3 {
+					img.Pix[i+c] = Quantize(buckets, uint8(min(float32(img.Pix[i+c])+offset, 255)))
+				}
+			}
+		}
+	})
+}
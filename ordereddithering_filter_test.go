@@ -0,0 +1,81 @@
+package main
+
+import (
+	"image"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestOrderedDitheringFilter(t *testing.T) {
+	t.Run("Bayer matrix", func(t *testing.T) {
+		if m := BayerMatrix(2); !slices.Equal(m, []int{0, 2, 3, 1}) {
+			t.Errorf("Unexpected 2x2 matrix %v", m)
+		}
+		for _, size := range []int{4, 8} {
+			m := slices.Sorted(slices.Values(BayerMatrix(size)))
+			for i, v := range m {
+				if v != i {
+					t.Fatalf("%dx%d matrix isn't every index once", size, size)
+				}
+			}
+		}
+	})
+	t.Run("Blue noise", func(t *testing.T) {
+		// Aim: every rank is used once, and the lowest ranks are spread out rather than bunched together
+		ranks := BlueNoiseRanks()
+		sorted := slices.Sorted(slices.Values(ranks))
+		for i, v := range sorted {
+			if v != i {
+				t.Fatal("Blue noise isn't every rank once")
+			}
+		}
+		// the first 1/16 of the pixels should be about 4 apart, so none should be right next to each other
+		lowest := BlueNoiseSize * BlueNoiseSize / 16
+		for p, rank := range ranks {
+			if rank >= lowest {
+				continue
+			}
+			x, y := p%BlueNoiseSize, p/BlueNoiseSize
+			for _, d := range [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}} {
+				nx := (x + d[0] + BlueNoiseSize) % BlueNoiseSize
+				ny := (y + d[1] + BlueNoiseSize) % BlueNoiseSize
+				if ranks[ny*BlueNoiseSize+nx] < lowest {
+					t.Fatalf("Low ranked pixels at %d,%d and %d,%d are touching", x, y, nx, ny)
+				}
+			}
+		}
+	})
+	t.Run("Average is kept", func(t *testing.T) {
+		// Aim: a flat grey comes out as a mix of the two levels either side that averages out to the same grey
+		for pattern := Bayer2; pattern <= BlueNoise; pattern++ {
+			img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+			for i := range img.Pix {
+				img.Pix[i] = 100
+			}
+			OrderedDitheringFilter{}.Apply(img, Params{"control.dithering.buckets": 2, "control.ordereddithering.pattern": float64(pattern)})
+			var sum float64
+			for i := 0; i < len(img.Pix); i += 4 {
+				v := img.Pix[i]
+				if v != 85 && v != 170 {
+					t.Fatalf("Pattern %d gave %d which isn't either side of 100", pattern, v)
+				}
+				sum += float64(v)
+			}
+			// a pattern with n thresholds can only get the mean to within 1/n of a bucket
+			levels := map[DitherPattern]float64{Bayer2: 4, Bayer4: 16, Bayer8: 64, BlueNoise: 4096}[pattern]
+			if mean := sum / float64(len(img.Pix)/4); math.Abs(mean-100) > 85/levels+0.5 {
+				t.Errorf("Pattern %d changed the mean from 100 to %v", pattern, mean)
+			}
+		}
+	})
+	t.Run("Bucket count is part of the cache key", func(t *testing.T) {
+		// Aim: the buckets belong to error diffusion dithering but changing them still has to re-run this stage
+		a := NewFilters()
+		b := NewFilters()
+		b.Params["control.dithering.buckets"] = 4
+		if stageKey(OrderedDitheringFilter{}, a) == stageKey(OrderedDitheringFilter{}, b) {
+			t.Error("Changing the buckets didn't change the stage key")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// MaxPaletteSize is the most colours a palette can have
+const MaxPaletteSize = 256
+
+// ColourDistance picks how the closest palette colour is found
+type ColourDistance int
+
+const (
+	DistanceRGB      ColourDistance = iota // straight line distance between the RGB values
+	DistanceLab                            // straight line distance in CIELAB, which is closer to how different colours look
+	DistanceWeighted                       // RGB distance weighted by how sensitive eyes are to each channel
+)
+
+// ToLab converts an sRGB colour to CIELAB with a D65 white point
+func ToLab(r, g, b uint8) [3]float64 {
+	lr, lg, lb := srgbToLinear[r], srgbToLinear[g], srgbToLinear[b]
+	// linear RGB to XYZ, relative to the white point
+	x := (0.4124*lr + 0.3576*lg + 0.1805*lb) / 0.95047
+	y := 0.2126*lr + 0.7152*lg + 0.0722*lb
+	z := (0.0193*lr + 0.1192*lg + 0.9505*lb) / 1.08883
+	f := func(t float64) float64 {
+		if t > 216.0/24389.0 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27.0*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// FromLab converts a CIELAB colour with a D65 white point back to sRGB, colours outside sRGB are clamped
+func FromLab(lab [3]float64) (r, g, b uint8) {
+	fy := (lab[0] + 16) / 116
+	fx, fz := fy+lab[1]/500, fy-lab[2]/200
+	f := func(t float64) float64 {
+		if t > 6.0/29.0 {
+			return t * t * t
+		}
+		return (116*t - 16) * 27.0 / 24389.0
+	}
+	x, y, z := f(fx)*0.95047, f(fy), f(fz)*1.08883
+	// XYZ to linear RGB, then the sRGB curve
+	return FromLinear(3.2406*x - 1.5372*y - 0.4986*z), FromLinear(-0.9689*x + 1.8758*y + 0.0415*z), FromLinear(0.0557*x - 0.2040*y + 1.0570*z)
+}
+
+// PaletteMatcher finds the closest colour in a palette
+type PaletteMatcher struct {
+	palette  []rl.Color
+	lab      [][3]float64 // the palette in CIELAB, only set when matching with DistanceLab
+	distance ColourDistance
+}
+
+func NewPaletteMatcher(palette []rl.Color, distance ColourDistance) PaletteMatcher {
+	m := PaletteMatcher{palette: palette, distance: distance}
+	if distance == DistanceLab {
+		m.lab = make([][3]float64, len(palette))
+		for i, c := range palette {
+			m.lab[i] = ToLab(c.R, c.G, c.B)
+		}
+	}
+	return m
+}
+
+// Closest returns the palette colour nearest to r, g, b
+func (m PaletteMatcher) Closest(r, g, b uint8) rl.Color {
+	var lab [3]float64
+	if m.distance == DistanceLab {
+		lab = ToLab(r, g, b)
+	}
+	best, bestDistance := 0, math.Inf(1)
+	for i, c := range m.palette {
+		var d float64
+		switch m.distance {
+		case DistanceLab:
+			dl, da, db := lab[0]-m.lab[i][0], lab[1]-m.lab[i][1], lab[2]-m.lab[i][2]
+			d = dl*dl + da*da + db*db
+		case DistanceWeighted:
+			// the "redmean" approximation, which weights red and blue by how red the two colours are
+			mean := (float64(r) + float64(c.R)) / 2
+			dr, dg, db := float64(r)-float64(c.R), float64(g)-float64(c.G), float64(b)-float64(c.B)
+			d = (2+mean/256)*dr*dr + 4*dg*dg + (2+(255-mean)/256)*db*db
+		default:
+			dr, dg, db := float64(r)-float64(c.R), float64(g)-float64(c.G), float64(b)-float64(c.B)
+			d = dr*dr + dg*dg + db*db
+		}
+		if d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return m.palette[best]
+}
+
+// BuiltInPalette is a palette that ships with the app
+type BuiltInPalette struct {
+	Name    string // translation key
+	Colours []rl.Color
+}
+
+// hexPalette makes a palette out of 0xRRGGBB values
+func hexPalette(values ...uint32) []rl.Color {
+	palette := make([]rl.Color, len(values))
+	for i, v := range values {
+		palette[i] = rl.NewColor(uint8(v>>16), uint8(v>>8), uint8(v), 255)
+	}
+	return palette
+}
+
+var BuiltInPalettes = []BuiltInPalette{
+	{Name: "window.palette.builtin.blackwhite", Colours: hexPalette(0x000000, 0xffffff)},
+	{Name: "window.palette.builtin.greys", Colours: hexPalette(0x000000, 0x555555, 0xaaaaaa, 0xffffff)},
+	{Name: "window.palette.builtin.gameboy", Colours: hexPalette(0x0f380f, 0x306230, 0x8bac0f, 0x9bbc0f)},
+	{Name: "window.palette.builtin.cga", Colours: hexPalette(0x000000, 0x55ffff, 0xff55ff, 0xffffff)},
+	{Name: "window.palette.builtin.pico8", Colours: hexPalette(
+		0x000000, 0x1d2b53, 0x7e2553, 0x008751, 0xab5236, 0x5f574f, 0xc2c3c7, 0xfff1e8,
+		0xff004d, 0xffa300, 0xffec27, 0x00e436, 0x29adff, 0x83769c, 0xff77a8, 0xffccaa,
+	)},
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"cmp"
+	"image"
+	"math/rand/v2"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ExtractionMethod picks how the dominant colours of an image are found
+type ExtractionMethod int
+
+const (
+	MedianCut ExtractionMethod = iota
+	KMeans
+	Octree
+)
+
+// maxExtractionSamples caps how many pixels are looked at, more than this barely changes the palette
+const maxExtractionSamples = 1 << 16
+
+// PaletteEntry is an extracted colour and the fraction of the image it covers
+type PaletteEntry struct {
+	Colour rl.Color
+	Share  float64
+}
+
+// samplePixels takes an evenly spread selection of the opaque pixels of img
+func samplePixels(img *image.RGBA) [][3]uint8 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	step := max(1, w*h/maxExtractionSamples)
+	pixels := make([][3]uint8, 0, min(w*h, maxExtractionSamples+1))
+	for n := 0; n < w*h; n += step {
+		i := (n/w)*img.Stride + (n%w)*4
+		if img.Pix[i+3] == 0 {
+			continue
+		}
+		pixels = append(pixels, [3]uint8{img.Pix[i+0], img.Pix[i+1], img.Pix[i+2]})
+	}
+	return pixels
+}
+
+// ExtractPalette finds up to n dominant colours of img, most common first
+func ExtractPalette(img *image.RGBA, method ExtractionMethod, n int) []PaletteEntry {
+	pixels := samplePixels(img)
+	if len(pixels) == 0 || n <= 0 {
+		return nil
+	}
+	var entries []PaletteEntry
+	switch method {
+	case KMeans:
+		entries = kMeans(pixels, n)
+	case Octree:
+		entries = octreeQuantize(pixels, n)
+	default:
+		entries = medianCut(pixels, n)
+	}
+	slices.SortStableFunc(entries, func(a, b PaletteEntry) int { return cmp.Compare(b.Share, a.Share) })
+	return entries
+}
+
+// meanColour averages pixels
+func meanColour(pixels [][3]uint8) rl.Color {
+	var sums [3]int
+	for _, p := range pixels {
+		sums[0] += int(p[0])
+		sums[1] += int(p[1])
+		sums[2] += int(p[2])
+	}
+	count := max(len(pixels), 1)
+	return rl.NewColor(uint8(sums[0]/count), uint8(sums[1]/count), uint8(sums[2]/count), 255)
+}
+
+// medianCut keeps splitting the box of colours with the widest range in half at its median until there are n boxes
+func medianCut(pixels [][3]uint8, n int) []PaletteEntry {
+	// widest returns the channel a box is most spread out along and how far
+	widest := func(box [][3]uint8) (channel, spread int) {
+		for c := range 3 {
+			lo, hi := 255, 0
+			for _, p := range box {
+				lo, hi = min(lo, int(p[c])), max(hi, int(p[c]))
+			}
+			if hi-lo > spread {
+				channel, spread = c, hi-lo
+			}
+		}
+		return channel, spread
+	}
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < n {
+		best, bestChannel, bestSpread := -1, 0, 0
+		for i, box := range boxes {
+			if channel, spread := widest(box); len(box) > 1 && spread > bestSpread {
+				best, bestChannel, bestSpread = i, channel, spread
+			}
+		}
+		// every box is a single colour so there's nothing left to split
+		if best < 0 {
+			break
+		}
+		box := boxes[best]
+		slices.SortFunc(box, func(a, b [3]uint8) int { return cmp.Compare(a[bestChannel], b[bestChannel]) })
+		// move the cut to the nearest change in value so pixels of the same colour stay together
+		mid := len(box) / 2
+		lo, hi := mid, mid
+		for lo > 0 && box[lo-1][bestChannel] == box[lo][bestChannel] {
+			lo--
+		}
+		for hi < len(box) && box[hi-1][bestChannel] == box[hi][bestChannel] {
+			hi++
+		}
+		if hi == len(box) || (lo > 0 && mid-lo < hi-mid) {
+			mid = lo
+		} else {
+			mid = hi
+		}
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+	entries := make([]PaletteEntry, len(boxes))
+	for i, box := range boxes {
+		entries[i] = PaletteEntry{Colour: meanColour(box), Share: float64(len(box)) / float64(len(pixels))}
+	}
+	return entries
+}
+
+func squaredDistance(a [3]uint8, b [3]float64) float64 {
+	dr, dg, db := float64(a[0])-b[0], float64(a[1])-b[1], float64(a[2])-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+// kMeans clusters the colours into n groups, starting from centres picked with k-means++.
+// The random numbers are seeded the same every time so the same image always gives the same palette
+func kMeans(pixels [][3]uint8, n int) []PaletteEntry {
+	rng := rand.New(rand.NewPCG(1, 2))
+	toCentre := func(p [3]uint8) [3]float64 { return [3]float64{float64(p[0]), float64(p[1]), float64(p[2])} }
+
+	// k-means++ picks each new centre with a chance weighted by how far pixels are from the existing ones
+	centres := [][3]float64{toCentre(pixels[rng.IntN(len(pixels))])}
+	distances := make([]float64, len(pixels))
+	for len(centres) < n {
+		var total float64
+		for i, p := range pixels {
+			distances[i] = squaredDistance(p, centres[0])
+			for _, c := range centres[1:] {
+				distances[i] = min(distances[i], squaredDistance(p, c))
+			}
+			total += distances[i]
+		}
+		// every pixel is already on a centre
+		if total == 0 {
+			break
+		}
+		target := rng.Float64() * total
+		chosen := len(pixels) - 1
+		for i, d := range distances {
+			target -= d
+			if target < 0 {
+				chosen = i
+				break
+			}
+		}
+		centres = append(centres, toCentre(pixels[chosen]))
+	}
+
+	assignments := make([]int, len(pixels))
+	counts := make([]int, len(centres))
+	for range 20 {
+		// assign every pixel to its nearest centre
+		changed := false
+		clear(counts)
+		for i, p := range pixels {
+			best, bestDistance := 0, squaredDistance(p, centres[0])
+			for c := 1; c < len(centres); c++ {
+				if d := squaredDistance(p, centres[c]); d < bestDistance {
+					best, bestDistance = c, d
+				}
+			}
+			if assignments[i] != best {
+				assignments[i], changed = best, true
+			}
+			counts[best]++
+		}
+		// move every centre to the middle of its pixels
+		sums := make([][3]float64, len(centres))
+		for i, p := range pixels {
+			for c := range 3 {
+				sums[assignments[i]][c] += float64(p[c])
+			}
+		}
+		for c := range centres {
+			if counts[c] > 0 {
+				centres[c] = [3]float64{sums[c][0] / float64(counts[c]), sums[c][1] / float64(counts[c]), sums[c][2] / float64(counts[c])}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	entries := make([]PaletteEntry, 0, len(centres))
+	for c, centre := range centres {
+		if counts[c] == 0 {
+			continue
+		}
+		colour := rl.NewColor(uint8(centre[0]+0.5), uint8(centre[1]+0.5), uint8(centre[2]+0.5), 255)
+		entries = append(entries, PaletteEntry{Colour: colour, Share: float64(counts[c]) / float64(len(pixels))})
+	}
+	return entries
+}
+
+// octreeNode is a cube of colour space, split into 8 smaller cubes by the next bit of each channel
+type octreeNode struct {
+	children [8]*octreeNode
+	count    int
+	sums     [3]int
+	leaf     bool
+	// whether the node has been added to the list of nodes that can be merged
+	listed bool
+}
+
+// octreeQuantize puts every colour into an octree 8 levels deep, then merges the deepest nodes into their parents
+// until there are only n leaves left
+func octreeQuantize(pixels [][3]uint8, n int) []PaletteEntry {
+	const depth = 8
+	root := &octreeNode{}
+	// nodes that have children, by level, so the deepest can be merged first
+	var levels [depth][]*octreeNode
+	leaves := 0
+	for _, p := range pixels {
+		node := root
+		for level := 0; level < depth && !node.leaf; level++ {
+			shift := depth - 1 - level
+			i := int(p[0]>>shift&1)<<2 | int(p[1]>>shift&1)<<1 | int(p[2]>>shift&1)
+			if node.children[i] == nil {
+				child := &octreeNode{leaf: level == depth-1}
+				if child.leaf {
+					leaves++
+				}
+				if !node.listed {
+					levels[level] = append(levels[level], node)
+					node.listed = true
+				}
+				node.children[i] = child
+			}
+			node = node.children[i]
+		}
+		node.count++
+		for c := range 3 {
+			node.sums[c] += int(p[c])
+		}
+	}
+
+	// merge the deepest nodes into their parents until there are few enough leaves
+	for level := depth - 1; level >= 0 && leaves > n; level-- {
+		// merge the nodes covering the fewest pixels first so the common colours survive
+		nodes := levels[level]
+		weight := func(node *octreeNode) int {
+			total := 0
+			for _, child := range node.children {
+				if child != nil {
+					total += child.count
+				}
+			}
+			return total
+		}
+		slices.SortStableFunc(nodes, func(a, b *octreeNode) int { return cmp.Compare(weight(a), weight(b)) })
+		for _, node := range nodes {
+			if leaves <= n {
+				break
+			}
+			for i, child := range node.children {
+				if child == nil {
+					continue
+				}
+				node.count += child.count
+				for c := range 3 {
+					node.sums[c] += child.sums[c]
+				}
+				node.children[i] = nil
+				leaves--
+			}
+			node.leaf = true
+			leaves++
+		}
+	}
+
+	var entries []PaletteEntry
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			if node.count > 0 {
+				colour := rl.NewColor(uint8(node.sums[0]/node.count), uint8(node.sums[1]/node.count), uint8(node.sums[2]/node.count), 255)
+				entries = append(entries, PaletteEntry{Colour: colour, Share: float64(node.count) / float64(len(pixels))})
+			}
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return entries
+}
+
+// PaletteShares works out what fraction of the opaque pixels of img are closest to each colour of palette
+func PaletteShares(img *image.RGBA, palette []rl.Color) []float64 {
+	shares := make([]float64, len(palette))
+	if len(palette) == 0 {
+		return shares
+	}
+	matcher := NewPaletteMatcher(palette, DistanceRGB)
+	// palette colours repeat within the same palette so count by index, not by colour
+	index := make(map[rl.Color]int, len(palette))
+	for i := len(palette) - 1; i >= 0; i-- {
+		index[palette[i]] = i
+	}
+	pixels := samplePixels(img)
+	for _, p := range pixels {
+		shares[index[matcher.Closest(p[0], p[1], p[2])]]++
+	}
+	for i := range shares {
+		shares[i] /= float64(max(len(pixels), 1))
+	}
+	return shares
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestExtractPalette(t *testing.T) {
+	// three flat bands of colour covering a half, a third and a sixth of the image
+	colours := []color.RGBA{{200, 30, 30, 255}, {20, 160, 40, 255}, {40, 40, 220, 255}}
+	bands := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 60, 30))
+		for y := range 30 {
+			for x := range 60 {
+				switch {
+				case x < 30:
+					img.SetRGBA(x, y, colours[0])
+				case x < 50:
+					img.SetRGBA(x, y, colours[1])
+				default:
+					img.SetRGBA(x, y, colours[2])
+				}
+			}
+		}
+		return img
+	}
+	shares := []float64{0.5, 1.0 / 3, 1.0 / 6}
+
+	for name, method := range map[string]ExtractionMethod{"median cut": MedianCut, "k-means": KMeans, "octree": Octree} {
+		t.Run("Dominant colours with "+name, func(t *testing.T) {
+			// Aim: asking for as many colours as there are should give exactly those colours, most common first
+			entries := ExtractPalette(bands(), method, 3)
+			if len(entries) != 3 {
+				t.Fatalf("Expected 3 colours, got %v", entries)
+			}
+			for i, e := range entries {
+				if e.Colour != colours[i] {
+					t.Errorf("Colour %d: expected %v, got %v", i, colours[i], e.Colour)
+				}
+				if math.Abs(e.Share-shares[i]) > 0.01 {
+					t.Errorf("Colour %d: expected a share of %v, got %v", i, shares[i], e.Share)
+				}
+			}
+		})
+		t.Run("Limited to n with "+name, func(t *testing.T) {
+			for _, n := range []int{1, 2, 8} {
+				entries := ExtractPalette(randomImage(40, 40), method, n)
+				if len(entries) == 0 || len(entries) > n {
+					t.Errorf("Asked for %d colours, got %d", n, len(entries))
+				}
+				var total float64
+				for _, e := range entries {
+					total += e.Share
+				}
+				if math.Abs(total-1) > 1e-9 {
+					t.Errorf("Shares add up to %v", total)
+				}
+			}
+		})
+	}
+	t.Run("Empty image", func(t *testing.T) {
+		if entries := ExtractPalette(image.NewRGBA(image.Rect(0, 0, 4, 4)), KMeans, 4); entries != nil {
+			t.Errorf("Expected nothing from a transparent image, got %v", entries)
+		}
+	})
+	t.Run("Palette shares", func(t *testing.T) {
+		palette := []rl.Color{colours[2], colours[0], colours[1]}
+		got := PaletteShares(bands(), palette)
+		expected := []float64{shares[2], shares[0], shares[1]}
+		if !slices.EqualFunc(got, expected, func(a, b float64) bool { return math.Abs(a-b) < 0.01 }) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+}
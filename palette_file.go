@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// PaletteFormat is a palette file format, picked by the file's extension
+type PaletteFormat int
+
+const (
+	GPL      PaletteFormat = iota // GIMP palette
+	ASE                           // Adobe swatch exchange
+	HEX                           // Lospec hex list
+	PaintNET                      // Paint.NET palette
+)
+
+// PaletteFormatFromPath works out a palette file's format from its extension
+func PaletteFormatFromPath(path string) (PaletteFormat, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpl":
+		return GPL, true
+	case ".ase":
+		return ASE, true
+	case ".hex":
+		return HEX, true
+	case ".txt":
+		return PaintNET, true
+	}
+	return 0, false
+}
+
+// IsPaletteFile checks whether path looks like a palette rather than an image
+func IsPaletteFile(path string) bool {
+	_, ok := PaletteFormatFromPath(path)
+	return ok
+}
+
+// LoadPaletteFile reads a palette in any of the supported formats
+func LoadPaletteFile(path string) ([]rl.Color, error) {
+	format, ok := PaletteFormatFromPath(path)
+	if !ok {
+		return nil, fmt.Errorf("unsupported palette extension %q", filepath.Ext(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	palette, err := DecodePalette(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %v: %w", filepath.Base(path), err)
+	}
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("%v has no colours in it", filepath.Base(path))
+	}
+	if len(palette) > MaxPaletteSize {
+		return nil, fmt.Errorf("%v has %d colours, the most that can be used is %d", filepath.Base(path), len(palette), MaxPaletteSize)
+	}
+	return palette, nil
+}
+
+// SavePaletteFile writes a palette in the format matching path's extension
+func SavePaletteFile(path string, palette []rl.Color) error {
+	format, ok := PaletteFormatFromPath(path)
+	if !ok {
+		return fmt.Errorf("unsupported palette extension %q", filepath.Ext(path))
+	}
+	var buf bytes.Buffer
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err := EncodePalette(&buf, palette, format, name); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// DecodePalette reads a palette in the given format
+func DecodePalette(r io.Reader, format PaletteFormat) ([]rl.Color, error) {
+	switch format {
+	case GPL:
+		return decodeGPL(r)
+	case ASE:
+		return decodeASE(r)
+	case HEX:
+		return decodeHex(r, 6)
+	case PaintNET:
+		return decodeHex(r, 8)
+	}
+	return nil, fmt.Errorf("unknown palette format %d", format)
+}
+
+// EncodePalette writes a palette in the given format, name is only used by formats that store one
+func EncodePalette(w io.Writer, palette []rl.Color, format PaletteFormat, name string) error {
+	switch format {
+	case GPL:
+		return encodeGPL(w, palette, name)
+	case ASE:
+		return encodeASE(w, palette)
+	case HEX:
+		for _, c := range palette {
+			if _, err := fmt.Fprintf(w, "%02x%02x%02x\n", c.R, c.G, c.B); err != nil {
+				return err
+			}
+		}
+		return nil
+	case PaintNET:
+		if _, err := fmt.Fprintf(w, ";paint.net Palette File\n;Colors: %d\n", len(palette)); err != nil {
+			return err
+		}
+		for _, c := range palette {
+			if _, err := fmt.Fprintf(w, "FF%02X%02X%02X\n", c.R, c.G, c.B); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown palette format %d", format)
+}
+
+// decodeGPL reads a GIMP palette, a header followed by lines of "R G B name"
+func decodeGPL(r io.Reader) ([]rl.Color, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "GIMP Palette" {
+		return nil, errors.New(`missing "GIMP Palette" header`)
+	}
+	var palette []rl.Color
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		// skip blank lines, comments and header fields like "Name: ..." and "Columns: ..."
+		if text == "" || strings.HasPrefix(text, "#") || strings.Contains(strings.SplitN(text, " ", 2)[0], ":") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected red, green and blue values", line)
+		}
+		var channels [3]uint8
+		for i := range channels {
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %q isn't a value from 0 to 255", line, fields[i])
+			}
+			channels[i] = uint8(v)
+		}
+		palette = append(palette, rl.NewColor(channels[0], channels[1], channels[2], 255))
+	}
+	return palette, scanner.Err()
+}
+
+func encodeGPL(w io.Writer, palette []rl.Color, name string) error {
+	if _, err := fmt.Fprintf(w, "GIMP Palette\nName: %s\nColumns: 8\n#\n", name); err != nil {
+		return err
+	}
+	for _, c := range palette {
+		if _, err := fmt.Fprintf(w, "%3d %3d %3d\t%s\n", c.R, c.G, c.B, HexCode(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeHex reads one hex colour per line, digits is 6 for RRGGBB or 8 for AARRGGBB. Lines starting with ; are comments
+func decodeHex(r io.Reader, digits int) ([]rl.Color, error) {
+	scanner := bufio.NewScanner(r)
+	var palette []rl.Color
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "#")
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		v, err := strconv.ParseUint(text, 16, 32)
+		if err != nil || len(text) != digits {
+			return nil, fmt.Errorf("line %d: %q isn't a %d digit hex colour", line, text, digits)
+		}
+		// the alpha of AARRGGBB is in the top byte so it's dropped along with the rest
+		palette = append(palette, rl.NewColor(uint8(v>>16), uint8(v>>8), uint8(v), 255))
+	}
+	return palette, scanner.Err()
+}
+
+// aseColour is the ASE block type holding a colour, the other blocks open and close groups
+const aseColour = 0x0001
+
+// decodeASE reads the colours out of an Adobe swatch exchange file, groups are flattened
+func decodeASE(r io.Reader) ([]rl.Color, error) {
+	var header struct {
+		Signature [4]byte
+		Major     uint16
+		Minor     uint16
+		Blocks    uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("couldn't read header: %w", err)
+	}
+	if string(header.Signature[:]) != "ASEF" {
+		return nil, errors.New(`missing "ASEF" signature`)
+	}
+	if header.Major != 1 {
+		return nil, fmt.Errorf("unsupported version %d.%d", header.Major, header.Minor)
+	}
+	var palette []rl.Color
+	for i := range header.Blocks {
+		var block struct {
+			Type   uint16
+			Length uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &block); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		// colour blocks are tiny, anything this big is a corrupt file rather than a reason to allocate gigabytes
+		if block.Length > 1<<16 {
+			return nil, fmt.Errorf("block %d: length %d is too long", i, block.Length)
+		}
+		body := make([]byte, block.Length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		if block.Type != aseColour {
+			continue
+		}
+		c, err := decodeASEColour(body)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		palette = append(palette, c)
+	}
+	return palette, nil
+}
+
+// decodeASEColour reads a colour block: a UTF-16 name, a colour model, its float values and a colour type
+func decodeASEColour(body []byte) (rl.Color, error) {
+	r := bytes.NewReader(body)
+	var nameLength uint16
+	if err := binary.Read(r, binary.BigEndian, &nameLength); err != nil {
+		return rl.Color{}, err
+	}
+	if _, err := r.Seek(int64(nameLength)*2, io.SeekCurrent); err != nil {
+		return rl.Color{}, err
+	}
+	var model [4]byte
+	if _, err := io.ReadFull(r, model[:]); err != nil {
+		return rl.Color{}, err
+	}
+	counts := map[string]int{"RGB ": 3, "CMYK": 4, "LAB ": 3, "Gray": 1}
+	count, ok := counts[string(model[:])]
+	if !ok {
+		return rl.Color{}, fmt.Errorf("unknown colour model %q", model)
+	}
+	values := make([]float32, count)
+	if err := binary.Read(r, binary.BigEndian, values); err != nil {
+		return rl.Color{}, err
+	}
+	channel := func(v float64) uint8 { return uint8(math.Round(min(max(v, 0), 1) * 255)) }
+	switch string(model[:]) {
+	case "RGB ":
+		return rl.NewColor(channel(float64(values[0])), channel(float64(values[1])), channel(float64(values[2])), 255), nil
+	case "CMYK":
+		k := 1 - float64(values[3])
+		return rl.NewColor(channel((1-float64(values[0]))*k), channel((1-float64(values[1]))*k), channel((1-float64(values[2]))*k), 255), nil
+	case "LAB ":
+		// ASE stores L from 0 to 1 rather than 0 to 100
+		r, g, b := FromLab([3]float64{float64(values[0]) * 100, float64(values[1]), float64(values[2])})
+		return rl.NewColor(r, g, b, 255), nil
+	default:
+		v := channel(float64(values[0]))
+		return rl.NewColor(v, v, v, 255), nil
+	}
+}
+
+// encodeASE writes every colour as an RGB block named after its hex code
+func encodeASE(w io.Writer, palette []rl.Color) error {
+	var buf bytes.Buffer
+	buf.WriteString("ASEF")
+	binary.Write(&buf, binary.BigEndian, []uint16{1, 0})
+	binary.Write(&buf, binary.BigEndian, uint32(len(palette)))
+	for _, c := range palette {
+		// names are null terminated UTF-16
+		name := utf16.Encode([]rune(HexCode(c) + "\x00"))
+		var body bytes.Buffer
+		binary.Write(&body, binary.BigEndian, uint16(len(name)))
+		binary.Write(&body, binary.BigEndian, name)
+		body.WriteString("RGB ")
+		binary.Write(&body, binary.BigEndian, []float32{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255})
+		// a normal colour rather than a global or spot one
+		binary.Write(&body, binary.BigEndian, uint16(2))
+
+		binary.Write(&buf, binary.BigEndian, uint16(aseColour))
+		binary.Write(&buf, binary.BigEndian, uint32(body.Len()))
+		buf.Write(body.Bytes())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
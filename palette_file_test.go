@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestPaletteFile(t *testing.T) {
+	palette := hexPalette(0x000000, 0x1d2b53, 0x7e2553, 0xff004d, 0xfff1e8)
+
+	for name, format := range map[string]PaletteFormat{"GPL": GPL, "ASE": ASE, "hex": HEX, "Paint.NET": PaintNET} {
+		t.Run("Round trip "+name, func(t *testing.T) {
+			// Aim: writing a palette and reading it back should give the same colours in the same order
+			var buf bytes.Buffer
+			if err := EncodePalette(&buf, palette, format, "test"); err != nil {
+				t.Fatalf("Couldn't encode: %v", err)
+			}
+			decoded, err := DecodePalette(&buf, format)
+			if err != nil {
+				t.Fatalf("Couldn't decode: %v", err)
+			}
+			if !slices.Equal(decoded, palette) {
+				t.Errorf("Expected %v, got %v", palette, decoded)
+			}
+		})
+	}
+
+	t.Run("Reading files from other programs", func(t *testing.T) {
+		// Aim: the header fields, comments and colour names real files have shouldn't be read as colours
+		cases := map[PaletteFormat]string{
+			GPL:      "GIMP Palette\nName: Test\nColumns: 4\n#\n# a comment\n  0   0   0\tBlack\n255 0 77 Untitled\n",
+			HEX:      "000000\r\n#ff004d\r\n\r\n",
+			PaintNET: ";paint.net Palette File\n;Palette Name: Test\nFF000000\n80FF004D\n",
+		}
+		for format, text := range cases {
+			decoded, err := DecodePalette(strings.NewReader(text), format)
+			if err != nil {
+				t.Fatalf("Couldn't decode format %d: %v", format, err)
+			}
+			if expected := hexPalette(0x000000, 0xff004d); !slices.Equal(decoded, expected) {
+				t.Errorf("Expected %v from format %d, got %v", expected, format, decoded)
+			}
+		}
+	})
+
+	t.Run("ASE colour models and groups", func(t *testing.T) {
+		// Aim: colours inside groups and in CMYK and grey should be converted to RGB
+		block := func(kind uint16, body []byte) []byte {
+			var buf bytes.Buffer
+			binary.Write(&buf, binary.BigEndian, kind)
+			binary.Write(&buf, binary.BigEndian, uint32(len(body)))
+			buf.Write(body)
+			return buf.Bytes()
+		}
+		colour := func(model string, values ...float32) []byte {
+			var buf bytes.Buffer
+			name := utf16.Encode([]rune("c\x00"))
+			binary.Write(&buf, binary.BigEndian, uint16(len(name)))
+			binary.Write(&buf, binary.BigEndian, name)
+			buf.WriteString(model)
+			binary.Write(&buf, binary.BigEndian, values)
+			binary.Write(&buf, binary.BigEndian, uint16(2))
+			return block(0x0001, buf.Bytes())
+		}
+		var file bytes.Buffer
+		file.WriteString("ASEF")
+		binary.Write(&file, binary.BigEndian, []uint16{1, 0})
+		binary.Write(&file, binary.BigEndian, uint32(5))
+		file.Write(block(0xc001, []byte{0, 1, 0, 0}))
+		file.Write(colour("CMYK", 0, 1, 1, 0))
+		file.Write(colour("Gray", 0.5))
+		file.Write(colour("LAB ", 1, 0, 0))
+		file.Write(block(0xc002, nil))
+
+		decoded, err := DecodePalette(&file, ASE)
+		if err != nil {
+			t.Fatalf("Couldn't decode: %v", err)
+		}
+		expected := []rl.Color{rl.NewColor(255, 0, 0, 255), rl.NewColor(128, 128, 128, 255), rl.NewColor(255, 255, 255, 255)}
+		if !slices.Equal(decoded, expected) {
+			t.Errorf("Expected %v, got %v", expected, decoded)
+		}
+	})
+
+	t.Run("Parse errors", func(t *testing.T) {
+		// Aim: broken files should give an error saying where the problem is instead of a palette or a panic
+		cases := []struct {
+			format   PaletteFormat
+			text     string
+			contains string
+		}{
+			{GPL, "not a palette\n", "GIMP Palette"},
+			{GPL, "GIMP Palette\n0 0 0\n300 0 0\n", "line 3"},
+			{GPL, "GIMP Palette\n0 0\n", "line 2"},
+			{HEX, "000000\nfff\n", "line 2"},
+			{HEX, "00000g\n", "line 1"},
+			{PaintNET, "FF000000\n000000\n", "line 2"},
+			{ASE, "ASEF", "header"},
+			{ASE, "RIFF\x00\x01\x00\x00\x00\x00\x00\x00", "ASEF"},
+			{ASE, "ASEF\x00\x01\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x10", "block 0"},
+			{ASE, "ASEF\x00\x01\x00\x00\x00\x00\x00\x01\x00\x01\xff\xff\xff\xff", "too long"},
+		}
+		for _, c := range cases {
+			_, err := DecodePalette(strings.NewReader(c.text), c.format)
+			if err == nil || !strings.Contains(err.Error(), c.contains) {
+				t.Errorf("Expected an error mentioning %q for %q, got %v", c.contains, c.text, err)
+			}
+		}
+	})
+
+	t.Run("Saving and loading files", func(t *testing.T) {
+		// Aim: the format should be picked from the extension, and files with no colours should be refused
+		dir := t.TempDir()
+		for _, name := range []string{"p.gpl", "p.ase", "p.hex", "p.txt"} {
+			path := filepath.Join(dir, name)
+			if err := SavePaletteFile(path, palette); err != nil {
+				t.Fatalf("Couldn't save %v: %v", name, err)
+			}
+			loaded, err := LoadPaletteFile(path)
+			if err != nil {
+				t.Fatalf("Couldn't load %v: %v", name, err)
+			}
+			if !slices.Equal(loaded, palette) {
+				t.Errorf("Expected %v from %v, got %v", palette, name, loaded)
+			}
+		}
+		if err := SavePaletteFile(filepath.Join(dir, "p.png"), palette); err == nil {
+			t.Error("Expected an error saving a palette as a png")
+		}
+		empty := filepath.Join(dir, "empty.hex")
+		os.WriteFile(empty, []byte("\n"), 0o644)
+		if _, err := LoadPaletteFile(empty); err == nil {
+			t.Error("Expected an error loading a palette with no colours")
+		}
+		if !IsPaletteFile("a/B.GPL") || IsPaletteFile("image.png") || IsPaletteFile("project"+ProjectExtension) {
+			t.Error("Palette files weren't told apart from images and projects")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"math"
 	"slices"
 	"strings"
@@ -15,10 +16,28 @@ type PaletteWindow struct {
 	Anchor          rl.Vector2
 	InteractedWith  time.Time
 	ActiveHistogram int32
+	// Palette extraction settings
+	ExtractionMethod int32
+	ExtractionCount  float32
+	// File used when importing or exporting
+	FilePath          string
+	IsFilePathEditing bool
+	// Result of the last action, shown next to the file controls
+	Status string
+}
+
+// report shows the result of an import or export, errors are shown as they are
+func (p *PaletteWindow) report(err error, success string) {
+	if err != nil {
+		ErrorLogf("Palette error: %v", err.Error())
+		p.Status = err.Error()
+		return
+	}
+	p.Status = Translate(success)
 }
 
 func (p *PaletteWindow) getRect() rl.Rectangle {
-	return rl.NewRectangle(p.Anchor.X, p.Anchor.Y, 796, 626)
+	return rl.NewRectangle(p.Anchor.X, p.Anchor.Y, 796, 716)
 	// return rl.NewRectangle(p.Anchor.X, p.Anchor.Y, 596, 426)
 }
 func (p *PaletteWindow) DrawHistogram(anchor rl.Vector2, data []int, colour rl.Color) {
@@ -37,7 +56,8 @@ func (p *PaletteWindow) DrawHistogram(anchor rl.Vector2, data []int, colour rl.C
 	// get metrics for drawing the graph
 	var GraphWidth = int32(p.getRect().Width - 20.0)
 	var BarWidth = int32(math.Floor(float64(GraphWidth / 256.0)))
-	var GraphHeight = int32(p.getRect().Height - 50.0)
+	// the palette goes underneath
+	var GraphHeight = int32(400)
 
 
 	// for each value in the channel
@@ -52,6 +72,44 @@ func (p *PaletteWindow) DrawHistogram(anchor rl.Vector2, data []int, colour rl.C
 		rl.DrawRectangle(x, int32(anchor.Y)+GraphHeight-height+20, BarWidth, height-25, colour)
 	}
 }
+
+// HexCode formats a colour as #RRGGBB
+func HexCode(c rl.Color) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// DrawPalette draws a grid of swatches for the current palette in bounds, clicking one copies its hex code
+func (p *PaletteWindow) DrawPalette(bounds rl.Rectangle) {
+	if len(state.ImagePalette) == 0 {
+		gui.Label(bounds, Translate("window.palette.nopalette"))
+		return
+	}
+	const columns = 8
+	rows := (len(state.ImagePalette) + columns - 1) / columns
+	width := bounds.Width / columns
+	height := min(55, bounds.Height/float32(rows))
+	for i, colour := range state.ImagePalette {
+		swatch := rl.NewRectangle(bounds.X+float32(i%columns)*width, bounds.Y+float32(i/columns)*height, width-5, height-5)
+		rl.DrawRectangleRec(swatch, colour)
+		rl.DrawRectangleLinesEx(swatch, 1, rl.Gray)
+		// only label the swatches when there's room for it
+		if swatch.Height >= 30 {
+			// pick whichever of black and white shows up on the swatch
+			text := rl.Black
+			if int(colour.R)+int(colour.G)+int(colour.B) < 384 {
+				text = rl.White
+			}
+			rl.DrawText(HexCode(colour), int32(swatch.X)+4, int32(swatch.Y)+4, 10, text)
+			if i < len(state.PaletteShares) {
+				rl.DrawText(fmt.Sprintf("%.1f%%", state.PaletteShares[i]*100), int32(swatch.X)+4, int32(swatch.Y)+18, 10, text)
+			}
+		}
+		if rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), swatch) {
+			rl.SetClipboardText(HexCode(colour))
+			p.Status = fmt.Sprintf("%s %s", Translate("window.palette.copied"), HexCode(colour))
+		}
+	}
+}
 func (p *PaletteWindow) Draw() {
 	p.Showing = !gui.WindowBox(p.getRect(), Translate("window.palette.title"))
 	
@@ -59,6 +117,37 @@ func (p *PaletteWindow) Draw() {
 	controlString := strings.Join(MapOut([]string{"colour.red", "colour.blue", "colour.green"}, Translate), ";")
 	p.ActiveHistogram = gui.ToggleGroup(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+30, 100, 20), controlString, p.ActiveHistogram)
 
+	// built in palettes for palette dithering
+	for i, palette := range BuiltInPalettes {
+		if gui.Button(rl.NewRectangle(p.Anchor.X+330+float32(i)*92, p.Anchor.Y+30, 87, 20), Translate(palette.Name)) {
+			state.UsePalette(palette.Colours)
+		}
+	}
+
+	// extract the dominant colours of the filtered image
+	methods := strings.Join(MapOut([]string{"window.palette.mediancut", "window.palette.kmeans", "window.palette.octree"}, Translate), ";")
+	p.ExtractionMethod = gui.ToggleGroup(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+460, 90, 20), methods, p.ExtractionMethod)
+	count := int(p.ExtractionCount)
+	p.ExtractionCount = gui.Slider(rl.NewRectangle(p.Anchor.X+300, p.Anchor.Y+460, 120, 20), "", fmt.Sprintf("%s: %d", Translate("window.palette.count"), count), p.ExtractionCount, 2, 32)
+	if gui.Button(rl.NewRectangle(p.Anchor.X+530, p.Anchor.Y+460, 120, 20), Translate("window.palette.extract")) {
+		state.ExtractPalette(ExtractionMethod(p.ExtractionMethod), count)
+		p.Status = ""
+	}
+
+	// import/export palette files
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+485, 60, 20), Translate("window.palette.file"))
+	if gui.TextBox(rl.NewRectangle(p.Anchor.X+70, p.Anchor.Y+485, 250, 20), &p.FilePath, 256, p.IsFilePathEditing) {
+		p.IsFilePathEditing = !p.IsFilePathEditing
+	}
+	if gui.Button(rl.NewRectangle(p.Anchor.X+330, p.Anchor.Y+485, 87, 20), Translate("window.palette.import")) {
+		p.report(state.ImportPalette(p.FilePath), "window.palette.imported")
+	}
+	if gui.Button(rl.NewRectangle(p.Anchor.X+422, p.Anchor.Y+485, 87, 20), Translate("window.palette.export")) {
+		p.report(state.ExportPalette(p.FilePath), "window.palette.exported")
+	}
+	gui.Label(rl.NewRectangle(p.Anchor.X+520, p.Anchor.Y+485, 270, 20), p.Status)
+	p.DrawPalette(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+515, p.getRect().Width-15, 195))
+
 	// draw the histogram
 	switch p.ActiveHistogram {
 	case 0:
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"image"
+)
+
+// Palette dithering swaps every pixel for the closest colour in Filters.Palette, optionally spreading the
+// difference with the error diffusion algorithm chosen for the dithering filter
+type PaletteDitheringFilter struct{}
+
+func (PaletteDitheringFilter) Name() string {
+	return "control.palettedithering"
+}
+func (PaletteDitheringFilter) Params() []FilterParam {
+	params := []FilterParam{
+		{Key: "control.palettedithering.distance", Kind: ParamChoice, Min: 0, Max: float64(DistanceWeighted), Default: float64(DistanceRGB), Options: []string{
+			"control.palettedithering.rgb",
+			"control.palettedithering.lab",
+			"control.palettedithering.weighted",
+		}},
+		{Key: "control.palettedithering.diffusion", Kind: ParamChoice, Min: 0, Max: 1, Default: 1, Options: []string{"control.palettedithering.nearest", "control.palettedithering.diffuse"}},
+	}
+	// the diffusion settings are shared with the dithering filter
+	for _, p := range (DitheringFilter{}).Params()[1:] {
+		p.Hidden = true
+		params = append(params, p)
+	}
+	return params
+}
+func (f PaletteDitheringFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyFilters(context.Background(), img, Filters{Params: p})
+}
+func (f PaletteDitheringFilter) ApplyFilters(ctx context.Context, img *image.RGBA, filters Filters) error {
+	if LinearLight(filters.Params) {
+		return applyLinear(ctx, f, img, filters)
+	}
+	return paletteDither(ctx, rgbaPixels(img), filters)
+}
+func (PaletteDitheringFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	return paletteDither(ctx, img.pixels(), f)
+}
+
+func paletteDither[S sample](ctx context.Context, px pixels[S], f Filters) error {
+	palette, p := f.Palette, f.Params
+	if len(palette) == 0 {
+		DebugLog("No palette to dither to")
+		return nil
+	}
+	matcher := NewPaletteMatcher(palette, ColourDistance(p.Int("control.palettedithering.distance")))
+
+	if p.Int("control.palettedithering.diffusion") == 1 {
+		return diffuseError(ctx, px, DiffusionAlgorithm(p.Int("control.dithering.algorithm")), p.Int("control.dithering.scan") == 1, func(c [3]float32) [3]uint8 {
+			closest := matcher.Closest(clampUint8(c[0]), clampUint8(c[1]), clampUint8(c[2]))
+			return [3]uint8{closest.R, closest.G, closest.B}
+		})
+	}
+	// without diffusion every pixel is independent
+	return ParallelRowsContext(ctx, px.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			pix := px.Pix[y*px.Stride : y*px.Stride+px.Width*4]
+			for i := 0; i < len(pix); i += 4 {
+				closest := matcher.Closest(px.sRGB(pix[i+0]), px.sRGB(pix[i+1]), px.sRGB(pix[i+2]))
+				pix[i+0], pix[i+1], pix[i+2] = px.fromSRGB(closest.R), px.fromSRGB(closest.G), px.fromSRGB(closest.B)
+			}
+		}
+	})
+}
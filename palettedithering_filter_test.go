@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"math"
+	"slices"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestPalette(t *testing.T) {
+	t.Run("Cache key includes the palette", func(t *testing.T) {
+		// Aim: the palette isn't a parameter but changing it still has to re-run palette dithering
+		a := NewFilters()
+		b := NewFilters()
+		b.Palette = BuiltInPalettes[0].Colours
+		if stageKey(PaletteDitheringFilter{}, a) == stageKey(PaletteDitheringFilter{}, b) {
+			t.Error("Changing the palette didn't change the stage key")
+		}
+	})
+	t.Run("Lab", func(t *testing.T) {
+		for _, tt := range []struct {
+			r, g, b  uint8
+			expected [3]float64
+		}{
+			{0, 0, 0, [3]float64{0, 0, 0}},
+			{255, 255, 255, [3]float64{100, 0, 0}},
+			{255, 0, 0, [3]float64{53.24, 80.09, 67.20}},
+		} {
+			lab := ToLab(tt.r, tt.g, tt.b)
+			for i := range lab {
+				if math.Abs(lab[i]-tt.expected[i]) > 0.05 {
+					t.Errorf("ToLab(%d, %d, %d) = %v, expected %v", tt.r, tt.g, tt.b, lab, tt.expected)
+					break
+				}
+			}
+		}
+	})
+	t.Run("Closest", func(t *testing.T) {
+		// Aim: colours already in the palette match themselves, and distances that disagree pick differently
+		palette := BuiltInPalettes[len(BuiltInPalettes)-1].Colours
+		for _, distance := range []ColourDistance{DistanceRGB, DistanceLab, DistanceWeighted} {
+			m := NewPaletteMatcher(palette, distance)
+			for _, c := range palette {
+				if got := m.Closest(c.R, c.G, c.B); got != c {
+					t.Errorf("Distance %d matched %v to %v", distance, c, got)
+				}
+			}
+		}
+		// a dark saturated blue is nearer black in RGB but nearer blue by eye
+		blues := []rl.Color{rl.NewColor(0, 0, 0, 255), rl.NewColor(0, 0, 255, 255)}
+		if got := NewPaletteMatcher(blues, DistanceRGB).Closest(0, 0, 120); got != blues[0] {
+			t.Errorf("RGB distance picked %v", got)
+		}
+		if got := NewPaletteMatcher(blues, DistanceLab).Closest(0, 0, 120); got != blues[1] {
+			t.Errorf("Lab distance picked %v", got)
+		}
+	})
+}
+
+func TestPaletteDitheringFilter(t *testing.T) {
+	apply := func(img *image.RGBA, palette []rl.Color, diffuse bool) {
+		f := NewFilters()
+		f.Palette = palette
+		f.Params["control.palettedithering.diffusion"] = 0
+		if diffuse {
+			f.Params["control.palettedithering.diffusion"] = 1
+		}
+		if err := (PaletteDitheringFilter{}).ApplyFilters(context.Background(), img, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Run("Only palette colours", func(t *testing.T) {
+		palette := BuiltInPalettes[len(BuiltInPalettes)-1].Colours
+		for _, diffuse := range []bool{false, true} {
+			img := randomImage(32, 32)
+			apply(img, palette, diffuse)
+			for i := 0; i < len(img.Pix); i += 4 {
+				c := rl.NewColor(img.Pix[i], img.Pix[i+1], img.Pix[i+2], 255)
+				if !slices.Contains(palette, c) {
+					t.Fatalf("Diffusion %v left %v which isn't in the palette", diffuse, c)
+				}
+			}
+		}
+	})
+	t.Run("Diffusion keeps the average", func(t *testing.T) {
+		// Aim: mid grey dithered to black and white should be about half white
+		img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+		for i := range img.Pix {
+			img.Pix[i] = 128
+		}
+		apply(img, BuiltInPalettes[0].Colours, true)
+		var sum float64
+		for i := 0; i < len(img.Pix); i += 4 {
+			sum += float64(img.Pix[i])
+		}
+		if mean := sum / float64(len(img.Pix)/4); math.Abs(mean-128) > 4 {
+			t.Errorf("Expected a mean of about 128, got %v", mean)
+		}
+	})
+	t.Run("No palette", func(t *testing.T) {
+		img := randomImage(8, 8)
+		expected := ToRGBA(img)
+		apply(img, nil, true)
+		if !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Image changed without a palette")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+)
+
+// Workers is how many goroutines the filters split their work across, setting it to 1 runs everything serially
+var Workers = runtime.NumCPU()
+
+// ParallelRows splits the rows [0, height) into bands and runs fn on each band across a pool of Workers goroutines.
+// fn must only write to the rows it's given so the bands don't race each other
+func ParallelRows(height int, fn func(y0, y1 int)) {
+	ParallelRowsContext(context.Background(), height, fn)
+}
+
+// ParallelRowsContext is ParallelRows but it stops handing out bands once ctx is cancelled and returns the
+// context's error, the rows that weren't reached are left as they were
+func ParallelRowsContext(ctx context.Context, height int, fn func(y0, y1 int)) error {
+	workers := max(1, min(Workers, height))
+	// use a few bands per worker so a slow band doesn't hold everything up, and so cancelling doesn't have to
+	// wait for a whole worker's share of the image
+	bandHeight := max(1, height/(workers*4))
+	if workers == 1 {
+		for y := 0; y < height; y += bandHeight {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fn(y, min(y+bandHeight, height))
+		}
+		return ctx.Err()
+	}
+	bands := make(chan [2]int, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for band := range bands {
+				// keep draining the bands after a cancel so the sender never blocks
+				if ctx.Err() == nil {
+					fn(band[0], band[1])
+				}
+			}
+		}()
+	}
+	for y := 0; y < height && ctx.Err() == nil; y += bandHeight {
+		bands <- [2]int{y, min(y+bandHeight, height)}
+	}
+	close(bands)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// ParallelPix runs fn over bands of whole rows of img.Pix at once, for filters that change every pixel on its own
+func ParallelPix(img *image.RGBA, fn func(pix []uint8)) {
+	// images without a stride are just a flat list of pixels so they can't be split into rows
+	if img.Stride == 0 {
+		fn(img.Pix)
+		return
+	}
+	ParallelRows(img.Rect.Dy(), func(y0, y1 int) {
+		// sub images can end part way through their last row
+		fn(img.Pix[y0*img.Stride : min(y1*img.Stride, len(img.Pix))])
+	})
+}
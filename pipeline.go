@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"image"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PipelineCache remembers the output of every stage of the last run, so when only a later stage's
+// parameters change the earlier stages don't have to be applied again
+type PipelineCache struct {
+	mu     sync.Mutex
+	seed   maphash.Seed
+	stages []cachedStage
+}
+
+type cachedStage struct {
+	// key covers the input image and every enabled stage up to and including this one
+	key    string
+	output stageImage
+}
+
+// stageImage is the image between two stages. While linear light is on it stays a LinearImage from the first
+// LinearFilter until a stage that isn't one, so it's only rounded to 8-bit sRGB at either end of the run. Only one
+// of rgba and linear is set
+type stageImage struct {
+	rgba   *image.RGBA
+	linear *LinearImage
+}
+
+// apply applies filter to s with the settings in f, converting s to or from linear light first if it needs to be
+func (s *stageImage) apply(ctx context.Context, filter Filter, f Filters) error {
+	if filter, ok := filter.(LinearFilter); ok && LinearLight(f.Params) {
+		if s.linear == nil {
+			s.rgba, s.linear = nil, ToLinearImage(s.rgba)
+		}
+		return filter.ApplyLinear(ctx, s.linear, f)
+	}
+	if s.rgba == nil {
+		s.rgba, s.linear = s.linear.RGBA(), nil
+	}
+	return applyFilter(ctx, filter, s.rgba, f)
+}
+
+// clone makes a copy of s that can be changed without affecting it
+func (s stageImage) clone() stageImage {
+	if s.linear != nil {
+		return stageImage{linear: s.linear.Clone()}
+	}
+	return stageImage{rgba: ToRGBA(s.rgba)}
+}
+
+// RGBA is s as 8-bit sRGB, it's s's own image unless s is in linear light
+func (s stageImage) RGBA() *image.RGBA {
+	if s.linear != nil {
+		return s.linear.RGBA()
+	}
+	return s.rgba
+}
+
+// sourceKey identifies the pixels of the input image
+func (c *PipelineCache) sourceKey(src *image.RGBA) string {
+	c.mu.Lock()
+	if c.seed == (maphash.Seed{}) {
+		c.seed = maphash.MakeSeed()
+	}
+	seed := c.seed
+	c.mu.Unlock()
+	return fmt.Sprintf("%v:%x", src.Rect, maphash.Bytes(seed, src.Pix))
+}
+
+// stageKey identifies a filter and the values of its parameters and any other settings it uses from f
+func stageKey(filter Filter, f Filters) string {
+	var sb strings.Builder
+	sb.WriteString(filter.Name())
+	for _, param := range filter.Params() {
+		fmt.Fprintf(&sb, ",%s=%v", param.Key, f.Params[param.Key])
+	}
+	// settings that aren't parameters are added by hand
+	switch filter.(type) {
+	case PaletteDitheringFilter:
+		fmt.Fprintf(&sb, ",palette=%v", f.Palette)
+	case CurvesFilter:
+		fmt.Fprintf(&sb, ",curves=%v", f.Curves)
+	}
+	return sb.String()
+}
+
+// Run applies filters to a copy of src, reusing as many cached stages as it can. It returns
+// the context's error if ctx is cancelled between or part way through stages
+func (c *PipelineCache) Run(ctx context.Context, src *image.RGBA, filters Filters) (*image.RGBA, error) {
+	// work out the key of every enabled stage
+	var stages []Filter
+	var keys []string
+	key := c.sourceKey(src)
+	for _, k := range filters.Order {
+		filter, ok := GetFilter(k)
+		if !ok {
+			ErrorLogf("Unknown filter in order: %v", k)
+			continue
+		}
+		if !filters.Enabled[k] {
+			continue
+		}
+		key += "|" + stageKey(filter, filters)
+		stages = append(stages, filter)
+		keys = append(keys, key)
+	}
+
+	// find the first stage that has changed and start from the output of the one before it
+	c.mu.Lock()
+	reused := 0
+	for reused < len(keys) && reused < len(c.stages) && c.stages[reused].key == keys[reused] {
+		reused++
+	}
+	cached := slices.Clone(c.stages[:reused])
+	c.mu.Unlock()
+
+	var img stageImage
+	if reused > 0 {
+		img = cached[reused-1].output.clone()
+	} else {
+		img = stageImage{rgba: ToRGBA(src)}
+	}
+	DebugLogf("Reusing %d of %d cached stages", reused, len(stages))
+
+	for i := reused; i < len(stages); i++ {
+		// stop early if nobody wants the result any more
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		t := time.Now()
+		// a stage that was stopped part way through isn't cached
+		if err := img.apply(ctx, stages[i], filters); err != nil {
+			return nil, err
+		}
+		InfoLogf("%v filter time: %v", stages[i].Name(), time.Since(t))
+		cached = append(cached, cachedStage{key: keys[i], output: img.clone()})
+	}
+
+	c.mu.Lock()
+	c.stages = cached
+	c.mu.Unlock()
+	return img.RGBA(), nil
+}
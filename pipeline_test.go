@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"slices"
+	"testing"
+)
+
+// countingFilter records how many times it's applied, and inverts the image so its output is visible
+type countingFilter struct {
+	applied *int
+}
+
+func (f countingFilter) Name() string { return "test.counting" }
+
+func (f countingFilter) Params() []FilterParam {
+	return []FilterParam{{Key: "test.counting.amount", Kind: ParamFloat, Min: 0, Max: 1, Default: 1}}
+}
+
+func (f countingFilter) Apply(img *image.RGBA, p Params) {
+	*f.applied++
+	for i := range img.Pix {
+		if i%4 != 3 {
+			img.Pix[i] = 255 - img.Pix[i]
+		}
+	}
+}
+
+func TestPipelineCache(t *testing.T) {
+	applied := 0
+	registry := FilterRegistry
+	FilterRegistry = append(slices.Clone(registry), countingFilter{&applied})
+	defer func() { FilterRegistry = registry }()
+
+	src := randomImage(32, 32)
+	filters := NewFilters()
+	filters.Enabled["test.counting"] = true
+	filters.Enabled["control.grayscale"] = true
+	// put the counting filter first so it's the stage that should be reused
+	filters.Order = append([]string{"test.counting"}, slices.DeleteFunc(filters.Order, func(k string) bool { return k == "test.counting" })...)
+
+	run := func(c *PipelineCache, f Filters) []uint8 {
+		res, err := c.Run(context.Background(), src, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the reference render shouldn't count towards the cached one
+		counted := applied
+		expected := ToRGBA(src)
+		f.Apply(expected)
+		applied = counted
+		if !bytes.Equal(res.Pix, expected.Pix) {
+			t.Error("Cached pipeline gave a different result to applying the filters directly")
+		}
+		return res.Pix
+	}
+
+	t.Run("Later stage changed", func(t *testing.T) {
+		// Aim: changing a stage after the counting filter shouldn't run the counting filter again
+		var c PipelineCache
+		run(&c, filters)
+		applied = 0
+		changed := filters.Clone()
+		changed.Params["control.brightness"] = 0.5
+		run(&c, changed)
+		if applied != 0 {
+			t.Errorf("Earlier stage was applied %d times, expected it to be reused", applied)
+		}
+	})
+	t.Run("Earlier stage changed", func(t *testing.T) {
+		// Aim: changing the stage's own parameters should recompute it
+		var c PipelineCache
+		run(&c, filters)
+		applied = 0
+		changed := filters.Clone()
+		changed.Params["test.counting.amount"] = 0.5
+		run(&c, changed)
+		if applied != 1 {
+			t.Errorf("Changed stage was applied %d times, expected 1", applied)
+		}
+	})
+	t.Run("Input changed", func(t *testing.T) {
+		// Aim: a different input image shouldn't reuse anything
+		var c PipelineCache
+		run(&c, filters)
+		applied = 0
+		src.Pix[0]++
+		run(&c, filters)
+		if applied != 1 {
+			t.Errorf("Stage was applied %d times after the input changed, expected 1", applied)
+		}
+	})
+	t.Run("Cache isn't modified", func(t *testing.T) {
+		// Aim: changing the returned image shouldn't affect later runs
+		var c PipelineCache
+		res := run(&c, filters)
+		clear(res)
+		run(&c, filters)
+	})
+}
+
+func TestPipelineResizingStages(t *testing.T) {
+	// Aim: a stage that changes the size of the image should carry on through the rest of the pipeline, both when
+	// applied directly and through the cache
+	src := randomImage(40, 20)
+	filters := NewFilters()
+	filters.Enabled["control.rotate"] = true
+	filters.Params["control.rotate.quarter"] = 1
+	filters.Enabled["control.crop"] = true
+	filters.Params["control.crop.left"] = 0.25
+	filters.Enabled["control.grayscale"] = true
+
+	direct := ToRGBA(src)
+	filters.Apply(direct)
+	var c PipelineCache
+	cached, err := c.Run(context.Background(), src, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// crop comes before rotate in the default order
+	if direct.Rect.Dx() != 20 || direct.Rect.Dy() != 30 {
+		t.Errorf("Expected a 20x30 image, got %v", direct.Rect)
+	}
+	if cached.Rect != direct.Rect || !bytes.Equal(cached.Pix, direct.Pix) {
+		t.Error("Cached pipeline gave a different result to applying the filters directly")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Reduces every channel to an exact number of levels spread evenly from 0 to 255
+type PosterizeFilter struct{}
+
+func (PosterizeFilter) Name() string {
+	return "control.posterize"
+}
+func (PosterizeFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.posterize.red", Kind: ParamInt, Min: 2, Max: 64, Default: 4},
+		{Key: "control.posterize.green", Kind: ParamInt, Min: 2, Max: 64, Default: 4},
+		{Key: "control.posterize.blue", Kind: ParamInt, Min: 2, Max: 64, Default: 4},
+	}
+}
+func (PosterizeFilter) Apply(img *image.RGBA, p Params) {
+	ApplyLUTs(img,
+		PosterizeLUT(p.Int("control.posterize.red")),
+		PosterizeLUT(p.Int("control.posterize.green")),
+		PosterizeLUT(p.Int("control.posterize.blue")),
+	)
+}
+
+// PosterizeLUT rounds every value to the nearest of levels values spread evenly from 0 to 255, which always
+// includes black and white. Fewer than 2 levels or more than 255 leaves everything alone
+func PosterizeLUT(levels int) LUT {
+	if levels < 2 || levels > 255 {
+		return IdentityLUT()
+	}
+	gaps := float64(levels - 1)
+	var l LUT
+	for v := range l {
+		l[v] = uint8(math.Round(math.Round(float64(v)*gaps/255) * 255 / gaps))
+	}
+	return l
+}
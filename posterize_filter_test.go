@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPosterizeFilter(t *testing.T) {
+	t.Run("Exact level count", func(t *testing.T) {
+		// Aim: n levels means exactly n different values come out, always including black and white
+		for _, levels := range []int{2, 3, 4, 7, 16, 64} {
+			l := PosterizeLUT(levels)
+			values := removeDuplicates(l[:])
+			if len(values) != levels {
+				t.Errorf("Expected %d levels, got %d: %v", levels, len(values), values)
+			}
+			if values[0] != 0 || values[len(values)-1] != 255 {
+				t.Errorf("Expected %d levels to reach black and white, got %v", levels, values)
+			}
+		}
+	})
+	t.Run("Rounds to the nearest level", func(t *testing.T) {
+		// Aim: with 3 levels the middle is grey and either side goes to black or white
+		l := PosterizeLUT(3)
+		for v, expected := range map[int]uint8{0: 0, 63: 0, 64: 128, 128: 128, 191: 128, 192: 255, 255: 255} {
+			if l[v] != expected {
+				t.Errorf("Expected %d to become %d, got %d", v, expected, l[v])
+			}
+		}
+	})
+	t.Run("Channels are separate", func(t *testing.T) {
+		// Aim: each channel uses its own level count
+		img := randomImage(16, 16)
+		p := NewFilters().Params
+		p["control.posterize.red"], p["control.posterize.green"], p["control.posterize.blue"] = 2, 3, 5
+		PosterizeFilter{}.Apply(img, p)
+		for c, levels := range []int{2, 3, 5} {
+			var values []uint8
+			for i := c; i < len(img.Pix); i += 4 {
+				values = append(values, img.Pix[i])
+			}
+			slices.Sort(values)
+			if n := len(slices.Compact(values)); n != levels {
+				t.Errorf("Expected channel %d to have %d levels, got %d", c, levels, n)
+			}
+		}
+	})
+}
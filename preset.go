@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Preset is a named set of filters that can be applied in one go
+type Preset struct {
+	Name    string
+	Filters Filters
+	// Built in presets ship with the app, they can't be renamed or deleted and aren't saved
+	BuiltIn bool `json:"-"`
+}
+
+// PresetLibrary holds the built in presets followed by the user's own
+type PresetLibrary struct {
+	Presets []Preset
+}
+
+// BuiltInPresets are the looks that ship with the app
+func BuiltInPresets() []Preset {
+	// error diffusion to a black and white palette, the dithering filter's buckets can't get down to 2 levels
+	newspaper := NewFilters()
+	newspaper.Enabled["control.grayscale"] = true
+	newspaper.Enabled["control.palettedithering"] = true
+	newspaper.Params["control.palettedithering.diffusion"] = 1
+	newspaper.Palette = slices.Clone(BuiltInPalettes[0].Colours)
+
+	retro := NewFilters()
+	retro.Enabled["control.quantizing"] = true
+	retro.Params["control.quantizationbands"] = 6
+	retro.Enabled["control.channeladjustment"] = true
+	retro.Params["control.channeladjustment.green"] = 0.9
+	retro.Params["control.channeladjustment.blue"] = 0.75
+	retro.Enabled["control.boxblur"] = true
+	retro.Params["control.boxblur.iterations"] = 1
+
+	noir := NewFilters()
+	noir.Enabled["control.grayscale"] = true
+	noir.Params["control.brightness"] = -0.15
+
+	return []Preset{
+		{Name: "1-bit newspaper", Filters: newspaper, BuiltIn: true},
+		{Name: "Soft retro", Filters: retro, BuiltIn: true},
+		{Name: "Noir", Filters: noir, BuiltIn: true},
+	}
+}
+
+// PresetsPath is where the user's presets are saved, next to the settings file
+func PresetsPath() (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "presets.json"), nil
+}
+
+// NewPresetLibrary makes a library with only the built in presets
+func NewPresetLibrary() PresetLibrary {
+	return PresetLibrary{Presets: BuiltInPresets()}
+}
+
+// Names of every preset in the order they're listed
+func (l *PresetLibrary) Names() []string {
+	names := make([]string, len(l.Presets))
+	for i, p := range l.Presets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Get a preset by name
+func (l *PresetLibrary) Get(name string) (Preset, bool) {
+	i := l.index(name)
+	if i < 0 {
+		return Preset{}, false
+	}
+	return l.Presets[i], true
+}
+
+func (l *PresetLibrary) index(name string) int {
+	return slices.IndexFunc(l.Presets, func(p Preset) bool { return p.Name == name })
+}
+
+// Save stores a copy of filters under name, replacing any user preset that already has that name
+func (l *PresetLibrary) Save(name string, filters Filters) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("presets need a name")
+	}
+	preset := Preset{Name: name, Filters: filters.Clone()}
+	i := l.index(name)
+	if i < 0 {
+		l.Presets = append(l.Presets, preset)
+		return nil
+	}
+	if l.Presets[i].BuiltIn {
+		return fmt.Errorf("%q is a built in preset", name)
+	}
+	l.Presets[i] = preset
+	return nil
+}
+
+// Rename a user preset
+func (l *PresetLibrary) Rename(oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	i := l.index(oldName)
+	switch {
+	case i < 0:
+		return fmt.Errorf("there's no preset called %q", oldName)
+	case l.Presets[i].BuiltIn:
+		return fmt.Errorf("%q is a built in preset", oldName)
+	case newName == "":
+		return errors.New("presets need a name")
+	case oldName != newName && l.index(newName) >= 0:
+		return fmt.Errorf("there's already a preset called %q", newName)
+	}
+	l.Presets[i].Name = newName
+	return nil
+}
+
+// Delete a user preset
+func (l *PresetLibrary) Delete(name string) error {
+	i := l.index(name)
+	if i < 0 {
+		return fmt.Errorf("there's no preset called %q", name)
+	}
+	if l.Presets[i].BuiltIn {
+		return fmt.Errorf("%q is a built in preset", name)
+	}
+	l.Presets = slices.Delete(l.Presets, i, i+1)
+	return nil
+}
+
+// userPresets are the presets that get saved and exported
+func (l *PresetLibrary) userPresets() []Preset {
+	var res []Preset
+	for _, p := range l.Presets {
+		if !p.BuiltIn {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Export writes the user's presets to a JSON file so they can be shared
+func (l *PresetLibrary) Export(path string) error {
+	content, err := json.MarshalIndent(l.userPresets(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// Import adds every preset in a JSON file written by Export, replacing user presets with the same name
+func (l *PresetLibrary) Import(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var presets []Preset
+	if err = json.Unmarshal(content, &presets); err != nil {
+		return fmt.Errorf("couldn't decode presets: %w", err)
+	}
+	var errs []error
+	for _, p := range presets {
+		// presets might be from a version with different filters
+		errs = append(errs, l.Save(p.Name, p.Filters.WithDefaults()))
+	}
+	return errors.Join(errs...)
+}
+
+// LoadPresetLibrary reads the user's presets from path on top of the built in ones, a missing file isn't an error
+func LoadPresetLibrary(path string) (PresetLibrary, error) {
+	l := NewPresetLibrary()
+	err := l.Import(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	return l, err
+}
+
+// SavePresetLibrary writes the user's presets to path, creating the folder if it needs to
+func SavePresetLibrary(path string, l *PresetLibrary) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return l.Export(path)
+}
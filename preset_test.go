@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPresetLibrary(t *testing.T) {
+	t.Run("Save, rename and delete", func(t *testing.T) {
+		l := NewPresetLibrary()
+		f := NewFilters()
+		f.Enabled["control.grayscale"] = true
+		if err := l.Save("Mine", f); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Rename("Mine", "Ours"); err != nil {
+			t.Fatal(err)
+		}
+		p, ok := l.Get("Ours")
+		if !ok || !p.Filters.Enabled["control.grayscale"] {
+			t.Errorf("Renamed preset lost its filters: %+v", p)
+		}
+		if err := l.Delete("Ours"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := l.Get("Ours"); ok {
+			t.Error("Deleted preset is still there")
+		}
+	})
+	t.Run("Built in presets are protected", func(t *testing.T) {
+		l := NewPresetLibrary()
+		name := BuiltInPresets()[0].Name
+		if l.Save(name, NewFilters()) == nil || l.Rename(name, "x") == nil || l.Delete(name) == nil {
+			t.Error("A built in preset was changed")
+		}
+	})
+	t.Run("Saved filters are a copy", func(t *testing.T) {
+		l := NewPresetLibrary()
+		f := NewFilters()
+		l.Save("Mine", f)
+		f.Enabled["control.boxblur"] = true
+		if p, _ := l.Get("Mine"); p.Filters.Enabled["control.boxblur"] {
+			t.Error("Preset changed along with the live filters")
+		}
+	})
+	t.Run("Export and import", func(t *testing.T) {
+		// Aim: exported presets should import into another library, without the built in ones
+		path := filepath.Join(t.TempDir(), "presets.json")
+		l := NewPresetLibrary()
+		f := NewFilters()
+		f.Params["control.dithering.buckets"] = 4
+		l.Save("Shared", f)
+		if err := l.Export(path); err != nil {
+			t.Fatal(err)
+		}
+		other := NewPresetLibrary()
+		if err := other.Import(path); err != nil {
+			t.Fatal(err)
+		}
+		builtIn := NewPresetLibrary()
+		expected := append(builtIn.Names(), "Shared")
+		if !slices.Equal(other.Names(), expected) {
+			t.Errorf("Expected %v, got %v", expected, other.Names())
+		}
+		if p, _ := other.Get("Shared"); p.Filters.Params.Int("control.dithering.buckets") != 4 {
+			t.Error("Imported preset lost its parameters")
+		}
+	})
+	t.Run("Missing library file", func(t *testing.T) {
+		l, err := LoadPresetLibrary(filepath.Join(t.TempDir(), "presets.json"))
+		if err != nil || len(l.Presets) != len(BuiltInPresets()) {
+			t.Errorf("Expected only the built in presets, got %v %v", l.Names(), err)
+		}
+	})
+}
+
+func TestBuiltInPresets(t *testing.T) {
+	t.Run("1-bit newspaper", func(t *testing.T) {
+		// Aim: the newspaper look should only ever be black or white
+		var preset Preset
+		for _, p := range BuiltInPresets() {
+			if p.Name == "1-bit newspaper" {
+				preset = p
+			}
+		}
+		img := randomImage(32, 32)
+		preset.Filters.Apply(img)
+		for i := 0; i < len(img.Pix); i += 4 {
+			for c := range 3 {
+				if v := img.Pix[i+c]; v != 0 && v != 255 {
+					t.Fatalf("Pixel %d has a level of %d", i/4, v)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	gui "github.com/gen2brain/raylib-go/raygui"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type PresetsWindow struct {
+	Showing        bool
+	Anchor         rl.Vector2
+	InteractedWith time.Time
+	ScrollIndex    int32
+	Active         int32
+	// Name used when saving or renaming
+	Name          string
+	IsNameEditing bool
+	// File used when importing or exporting
+	FilePath          string
+	IsFilePathEditing bool
+	// Result of the last action, shown at the bottom of the window
+	Status string
+}
+
+func (p *PresetsWindow) getRect() rl.Rectangle {
+	return rl.NewRectangle(p.Anchor.X, p.Anchor.Y, 400, 420)
+}
+
+// selected is the name of the highlighted preset, or "" if there isn't one
+func (p *PresetsWindow) selected() string {
+	names := state.Presets.Names()
+	if p.Active < 0 || int(p.Active) >= len(names) {
+		return ""
+	}
+	return names[p.Active]
+}
+
+// report shows the result of an action in the window
+func (p *PresetsWindow) report(err error, success string) {
+	if err != nil {
+		ErrorLogf("Preset error: %v", err.Error())
+		p.Status = err.Error()
+		return
+	}
+	p.Status = Translate(success)
+}
+
+// Draw the presets window
+func (p *PresetsWindow) Draw() {
+	p.Showing = !gui.WindowBox(p.getRect(), Translate("window.presets.title"))
+	// List of every preset
+	p.Active = gui.ListView(
+		rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+30, 180, 250),
+		strings.Join(state.Presets.Names(), ";"),
+		&p.ScrollIndex,
+		p.Active,
+	)
+	// Apply button
+	if gui.Button(rl.NewRectangle(p.Anchor.X+200, p.Anchor.Y+30, 190, 30), Translate("window.presets.apply")) {
+		p.report(state.ApplyPreset(p.selected()), "window.presets.applied")
+	}
+	// Delete button
+	if gui.Button(rl.NewRectangle(p.Anchor.X+200, p.Anchor.Y+70, 190, 30), Translate("window.presets.delete")) {
+		p.report(state.Presets.Delete(p.selected()), "window.presets.deleted")
+		state.SavePresets()
+	}
+	// Name text box
+	gui.Label(rl.NewRectangle(p.Anchor.X+200, p.Anchor.Y+110, 190, 20), Translate("window.presets.name"))
+	if gui.TextBox(rl.NewRectangle(p.Anchor.X+200, p.Anchor.Y+130, 190, 30), &p.Name, 64, p.IsNameEditing) {
+		p.IsNameEditing = !p.IsNameEditing
+	}
+	// Save current filters button
+	if gui.Button(rl.NewRectangle(p.Anchor.X+200, p.Anchor.Y+170, 190, 30), Translate("window.presets.save")) {
+		p.report(state.Presets.Save(p.Name, state.Filters), "window.presets.saved")
+		state.SavePresets()
+	}
+	// Rename button
+	if gui.Button(rl.NewRectangle(p.Anchor.X+200, p.Anchor.Y+210, 190, 30), Translate("window.presets.rename")) {
+		p.report(state.Presets.Rename(p.selected(), p.Name), "window.presets.renamed")
+		state.SavePresets()
+	}
+	// Import/export file text box
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+290, 380, 20), Translate("window.presets.file"))
+	if gui.TextBox(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+310, 380, 30), &p.FilePath, 256, p.IsFilePathEditing) {
+		p.IsFilePathEditing = !p.IsFilePathEditing
+	}
+	// Import button
+	if gui.Button(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+350, 185, 30), Translate("window.presets.import")) {
+		p.report(state.Presets.Import(p.FilePath), "window.presets.imported")
+		state.SavePresets()
+	}
+	// Export button
+	if gui.Button(rl.NewRectangle(p.Anchor.X+205, p.Anchor.Y+350, 185, 30), Translate("window.presets.export")) {
+		p.report(state.Presets.Export(p.FilePath), "window.presets.exported")
+	}
+	gui.Label(rl.NewRectangle(p.Anchor.X+10, p.Anchor.Y+390, 380, 20), p.Status)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ProjectExtension is the extension for saved editing sessions
+const ProjectExtension = ".nea"
+
+// ProjectVersion is bumped whenever the project format changes in a way older versions can't read
+const ProjectVersion = 1
+
+// Project is everything needed to pick up an edit where it was left, saved as JSON
+type Project struct {
+	Version int
+	// Path to the source image, relative paths are from the project file's folder
+	ImagePath string `json:",omitempty"`
+	// PNG copy of the source image so the project works on other machines, used instead of ImagePath if it's set
+	EmbeddedImage []byte `json:",omitempty"`
+	Filters       Filters
+	FileFormat    FileFormat
+}
+
+// IsProjectFile checks whether path looks like a project rather than an image
+func IsProjectFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ProjectExtension
+}
+
+// SaveProject writes the project as JSON to path
+func SaveProject(path string, p Project) error {
+	content, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// LoadProjectFile reads a project and fills in anything it's missing with defaults
+func LoadProjectFile(path string) (Project, error) {
+	var p Project
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err = json.Unmarshal(content, &p); err != nil {
+		return p, fmt.Errorf("couldn't decode project: %w", err)
+	}
+	if p.Version > ProjectVersion {
+		return p, fmt.Errorf("project version %d is newer than the supported version %d", p.Version, ProjectVersion)
+	}
+	if p.ImagePath == "" && len(p.EmbeddedImage) == 0 {
+		return p, errors.New("project has no image")
+	}
+	// relative image paths are from wherever the project is
+	if p.ImagePath != "" && !filepath.IsAbs(p.ImagePath) {
+		p.ImagePath = filepath.Join(filepath.Dir(path), p.ImagePath)
+	}
+	p.Filters = p.Filters.WithDefaults()
+	return p, nil
+}
+
+// LoadImage gets the project's source image, preferring the embedded copy
+func (p Project) LoadImage() (image.Image, error) {
+	if len(p.EmbeddedImage) > 0 {
+		return png.Decode(bytes.NewReader(p.EmbeddedImage))
+	}
+	return DecodeImageFile(p.ImagePath)
+}
+
+// WithDefaults fills in any filters or parameters missing from f, e.g. ones added since f was saved,
+// drops any that aren't registered any more and clamps the rest to their ranges
+func (f Filters) WithDefaults() Filters {
+	res := NewFilters()
+	for k, v := range f.Enabled {
+		if _, ok := res.Enabled[k]; ok {
+			res.Enabled[k] = v
+		}
+	}
+	// hand edited or old files can hold values the filters can't cope with, like a choice past the last option
+	// or a size of 0, so everything is brought back into range
+	for _, filter := range FilterRegistry {
+		for _, p := range filter.Params() {
+			v, ok := f.Params[p.Key]
+			if !ok {
+				continue
+			}
+			v = Clamp(v, p.Min, p.Max)
+			if p.Kind == ParamChoice {
+				v = math.Trunc(v)
+			}
+			res.Params[p.Key] = v
+		}
+	}
+	// keep the saved order, with any new filters at the end
+	order := make([]string, 0, len(res.Order))
+	for _, k := range f.Order {
+		if _, ok := GetFilter(k); ok && !slices.Contains(order, k) {
+			order = append(order, k)
+		}
+	}
+	for _, k := range res.Order {
+		if !slices.Contains(order, k) {
+			order = append(order, k)
+		}
+	}
+	res.Order = order
+	res.Palette = slices.Clone(f.Palette[:min(len(f.Palette), MaxPaletteSize)])
+	res.Curves = f.Curves.Clone()
+	return res
+}
+
+// NewProject captures the current editing session to be saved at path, embed stores a copy of the image in the
+// project. Otherwise the image's path is stored relative to the project so the folder can be moved or shared
+func (s *State) NewProject(path string, embed bool) (Project, error) {
+	p := Project{
+		Version:    ProjectVersion,
+		Filters:    s.Filters.Clone(),
+		FileFormat: s.Config.GetActiveFileFormat(),
+	}
+	if embed {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, &s.OrigImage); err != nil {
+			return p, err
+		}
+		p.EmbeddedImage = buf.Bytes()
+	} else {
+		if s.ImagePath == "" {
+			return p, errors.New("the image has no file path, embed it instead")
+		}
+		imagePath, err := filepath.Abs(s.ImagePath)
+		if err != nil {
+			return p, err
+		}
+		p.ImagePath = imagePath
+		// images on another drive can't be reached relatively so they stay absolute
+		if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+			if rel, err := filepath.Rel(dir, imagePath); err == nil {
+				p.ImagePath = rel
+			}
+		}
+	}
+	return p, nil
+}
+
+// SaveProject saves the current editing session to path
+func (s *State) SaveProject(path string, embed bool) error {
+	InfoLogf("Saving project to %v", path)
+	p, err := s.NewProject(path, embed)
+	if err != nil {
+		return err
+	}
+	return SaveProject(path, p)
+}
+
+// LoadProject opens a saved editing session, replacing the current image and filters
+func (s *State) LoadProject(path string) error {
+	InfoLogf("Loading project %v", path)
+	p, err := LoadProjectFile(path)
+	if err != nil {
+		return err
+	}
+	img, err := p.LoadImage()
+	if err != nil {
+		return fmt.Errorf("couldn't load project image: %w", err)
+	}
+	s.ImagePath = p.ImagePath
+	s.Filters = p.Filters
+	s.Config.FileFormat = p.FileFormat
+	s.Config.ActiveFormatIndex = int32(p.FileFormat)
+	s.History.Record(s.Filters)
+	s.LoadImage(img)
+	s.ImageLoaded = true
+	s.RefreshImage()
+	return nil
+}
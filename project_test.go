@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestProjectRoundTrip(t *testing.T) {
+	t.Run("Embedded image", func(t *testing.T) {
+		// Aim: a saved project should load back with the same image, filters, order and format
+		dir := t.TempDir()
+		path := filepath.Join(dir, "edit"+ProjectExtension)
+		s := State{OrigImage: *randomImage(8, 4), Filters: NewFilters()}
+		// PNG stores straight alpha so only opaque pixels round trip exactly
+		for i := 3; i < len(s.OrigImage.Pix); i += 4 {
+			s.OrigImage.Pix[i] = 255
+		}
+		s.Filters.Enabled["control.boxblur"] = true
+		s.Filters.Params["control.brightness"] = 0.25
+		s.Filters.Order[0], s.Filters.Order[1] = s.Filters.Order[1], s.Filters.Order[0]
+		s.Config.ActiveFormatIndex = int32(BMP)
+
+		if err := s.SaveProject(path, true); err != nil {
+			t.Fatal(err)
+		}
+		p, err := LoadProjectFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !filtersEqual(p.Filters, s.Filters) {
+			t.Errorf("Expected filters %+v, got %+v", s.Filters, p.Filters)
+		}
+		if p.FileFormat != BMP {
+			t.Errorf("Expected format %v, got %v", BMP, p.FileFormat)
+		}
+		img, err := p.LoadImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ToRGBA(img).Pix, s.OrigImage.Pix) {
+			t.Error("Embedded image doesn't match the original")
+		}
+	})
+	t.Run("Relative image path", func(t *testing.T) {
+		// Aim: image paths are relative to the project so a folder can be shared
+		dir := t.TempDir()
+		f, err := os.Create(filepath.Join(dir, "in.png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, randomImage(3, 3))
+		f.Close()
+		path := filepath.Join(dir, "edit"+ProjectExtension)
+		if err = SaveProject(path, Project{Version: ProjectVersion, ImagePath: "in.png", Filters: NewFilters()}); err != nil {
+			t.Fatal(err)
+		}
+		p, err := LoadProjectFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = p.LoadImage(); err != nil {
+			t.Error("Couldn't load the image next to the project:", err)
+		}
+	})
+	t.Run("Image path is stored relative", func(t *testing.T) {
+		// Aim: the image path written into a project should be relative to it, even when it's in a different folder
+		dir := t.TempDir()
+		imagePath := filepath.Join(dir, "images", "in.png")
+		if err := os.Mkdir(filepath.Dir(imagePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(imagePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, randomImage(3, 3))
+		f.Close()
+		if err = os.Mkdir(filepath.Join(dir, "projects"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "projects", "edit"+ProjectExtension)
+		s := State{ImagePath: imagePath, Filters: NewFilters()}
+		p, err := s.NewProject(path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := filepath.Join("..", "images", "in.png"); p.ImagePath != expected {
+			t.Errorf("Expected image path %v, got %v", expected, p.ImagePath)
+		}
+		if err = s.SaveProject(path, false); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadProjectFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = loaded.LoadImage(); err != nil {
+			t.Error("Couldn't load the image from the saved project:", err)
+		}
+	})
+	t.Run("Newer version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "edit"+ProjectExtension)
+		SaveProject(path, Project{Version: ProjectVersion + 1, ImagePath: "in.png"})
+		if _, err := LoadProjectFile(path); err == nil {
+			t.Error("Expected an error loading a newer project version")
+		}
+	})
+}
+
+func TestFiltersWithDefaults(t *testing.T) {
+	// Aim: projects saved before a filter existed should still load with every filter available
+	saved := Filters{
+		Enabled: map[string]bool{"control.grayscale": true, "control.removed": true},
+		Params:  Params{"control.boxblur.iterations": 7},
+		Order:   []string{"control.boxblur", "control.removed", "control.grayscale"},
+	}
+	f := saved.WithDefaults()
+	if !f.Enabled["control.grayscale"] || f.Params.Int("control.boxblur.iterations") != 7 {
+		t.Errorf("Saved values were lost: %+v", f)
+	}
+	if _, ok := f.Enabled["control.removed"]; ok {
+		t.Error("Unregistered filter was kept")
+	}
+	if f.Params["control.channeladjustment.red"] != 1 {
+		t.Error("Missing parameter wasn't given its default")
+	}
+	if len(f.Order) != len(FilterRegistry) || !slices.Equal(f.Order[:2], []string{"control.boxblur", "control.grayscale"}) {
+		t.Errorf("Unexpected order %v", f.Order)
+	}
+}
+
+func TestFiltersWithDefaultsRanges(t *testing.T) {
+	// Aim: out of range values from a hand edited project shouldn't reach the filters, where they could crash them
+	saved := Filters{Params: Params{
+		"control.ordereddithering.pattern": -3,
+		"control.clahe.tilesize":           0,
+		"control.quantizationbands":        300,
+		"control.gaussianblur.edgemode":    1.7,
+	}}
+	f := saved.WithDefaults()
+	for key, expected := range map[string]float64{
+		"control.ordereddithering.pattern": 0,
+		"control.clahe.tilesize":           8,
+		"control.quantizationbands":        16,
+		"control.gaussianblur.edgemode":    1,
+	} {
+		if f.Params[key] != expected {
+			t.Errorf("Expected %v to be %v, got %v", key, expected, f.Params[key])
+		}
+	}
+	// every filter should run on what's left without panicking
+	for k := range f.Enabled {
+		f.Enabled[k] = true
+	}
+	f.Apply(randomImage(8, 8))
+}
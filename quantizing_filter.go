@@ -0,0 +1,24 @@
+package main
+
+import "image"
+
+type QuantizingFilter struct{}
+
+func (QuantizingFilter) Name() string {
+	return "control.quantizing"
+}
+func (QuantizingFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.quantizationbands", Kind: ParamInt, Min: 3, Max: 16, Default: 16},
+	}
+}
+func (QuantizingFilter) Apply(img *image.RGBA, p Params) {
+	DebugLog("Quantizing filter applied")
+	// Quantize's levels can stop short of white, saved projects and presets like Soft retro rely on that
+	bands := uint8(Clamp(p.Int("control.quantizationbands"), 3, 16))
+	var lut LUT
+	for v := range lut {
+		lut[v] = Quantize(bands, uint8(v))
+	}
+	ApplyLUTs(img, lut, lut, lut)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestQuantizingFilter(t *testing.T) {
+	t.Run("Levels", func(t *testing.T) {
+		// Aim: the levels are multiples of the bucket size, with 6 bands of 36 white comes out as 252 like it always has
+		img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+		copy(img.Pix, []uint8{255, 100, 35, 255, 36, 71, 0, 255, 250, 251, 252, 255})
+		p := NewFilters().Params
+		p["control.quantizationbands"] = 6
+		QuantizingFilter{}.Apply(img, p)
+		expected := []uint8{252, 72, 0, 255, 36, 36, 0, 255, 216, 216, 252, 255}
+		for i, v := range expected {
+			if img.Pix[i] != v {
+				t.Fatalf("Expected %v, got %v", expected, img.Pix)
+			}
+		}
+	})
+	t.Run("Out of range bands", func(t *testing.T) {
+		// Aim: band counts past the slider's range, which would make the buckets 0 wide, shouldn't panic
+		p := NewFilters().Params
+		for _, bands := range []float64{-1, 0, 255, 300} {
+			p["control.quantizationbands"] = bands
+			QuantizingFilter{}.Apply(randomImage(4, 4), p)
+		}
+	})
+}
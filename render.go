@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"image"
+	"sync"
+	"time"
+)
+
+// Renderer applies the filters on a background goroutine so the UI doesn't freeze while they run.
+// Only the latest render matters, starting a new one cancels whichever one is already running
+type Renderer struct {
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	generation uint64
+	finished   *image.RGBA // the most recent finished frame that hasn't been picked up yet
+	cache      PipelineCache
+}
+
+// Start rendering a copy of src with a snapshot of filters, cancelling any render that's still going
+func (r *Renderer) Start(src *image.RGBA, filters Filters) {
+	// take the snapshot now so the UI can carry on changing the filters while this runs
+	img := ToRGBA(src)
+	filters = filters.Clone()
+
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.generation++
+	generation := r.generation
+	// anything that finished before now is out of date
+	r.finished = nil
+	r.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		t := time.Now()
+		res, err := r.cache.Run(ctx, img, filters)
+		if err != nil {
+			DebugLogf("Render %d cancelled", generation)
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// a newer render was started while this one was running so throw this one away
+		if generation != r.generation {
+			return
+		}
+		InfoLogf("Render %d time: %v", generation, time.Since(t))
+		r.finished = res
+	}()
+}
+
+// Poll returns the latest finished frame if one has arrived since the last call, it never blocks
+func (r *Renderer) Poll() (*image.RGBA, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	img := r.finished
+	r.finished = nil
+	return img, img != nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// waitForRender polls r until a frame arrives or it times out
+func waitForRender(t *testing.T, r *Renderer) []uint8 {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if img, ok := r.Poll(); ok {
+			return img.Pix
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Render never finished")
+	return nil
+}
+
+func TestRenderer(t *testing.T) {
+	t.Run("Latest render wins", func(t *testing.T) {
+		// Aim: when renders are started back to back only the last one's result should come out
+		src := randomImage(64, 64)
+		var r Renderer
+
+		slow := NewFilters()
+		slow.Enabled["control.boxblur"] = true
+		slow.Params["control.boxblur.iterations"] = 10
+		r.Start(src, slow)
+
+		latest := NewFilters()
+		latest.Enabled["control.grayscale"] = true
+		r.Start(src, latest)
+
+		expected := ToRGBA(src)
+		latest.Apply(expected)
+		if res := waitForRender(t, &r); !bytes.Equal(res, expected.Pix) {
+			t.Error("Received a frame that wasn't from the latest render")
+		}
+		// the slow render shouldn't turn up afterwards either
+		time.Sleep(50 * time.Millisecond)
+		if _, ok := r.Poll(); ok {
+			t.Error("Received a second frame from a cancelled render")
+		}
+	})
+	t.Run("Snapshot of filters", func(t *testing.T) {
+		// Aim: changing the filters after starting a render shouldn't affect that render
+		src := randomImage(16, 16)
+		var r Renderer
+		filters := NewFilters()
+		r.Start(src, filters)
+		filters.Enabled["control.grayscale"] = true
+
+		if res := waitForRender(t, &r); !bytes.Equal(res, src.Pix) {
+			t.Error("Render used filters changed after it started")
+		}
+	})
+}
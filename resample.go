@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// ResampleMethod picks how the colour between source pixels is worked out when resizing or rotating
+type ResampleMethod int
+
+const (
+	ResampleNearest  ResampleMethod = iota // the closest pixel, keeps hard edges so it's best for pixel art
+	ResampleBilinear                       // a straight line between the two nearest pixels in each direction
+	ResampleBicubic                        // a Catmull-Rom curve through the four nearest pixels, sharper than bilinear
+	ResampleLanczos                        // a windowed sinc over the six nearest pixels, the sharpest but it can ring
+)
+
+// ResampleOptions are the translation keys for each ResampleMethod, for use as FilterParam.Options
+var ResampleOptions = []string{"control.resample.nearest", "control.resample.bilinear", "control.resample.bicubic", "control.resample.lanczos"}
+
+// resampleKernel is a 1D filter that's 0 further than support pixels from its centre
+type resampleKernel struct {
+	support float64
+	weight  func(x float64) float64
+}
+
+var resampleKernels = map[ResampleMethod]resampleKernel{
+	// exactly one pixel is ever within half a pixel either side
+	ResampleNearest: {0.5, func(x float64) float64 {
+		if x > -0.5 && x <= 0.5 {
+			return 1
+		}
+		return 0
+	}},
+	ResampleBilinear: {1, func(x float64) float64 {
+		return max(0, 1-math.Abs(x))
+	}},
+	ResampleBicubic: {2, func(x float64) float64 {
+		x = math.Abs(x)
+		switch {
+		case x < 1:
+			return 1.5*x*x*x - 2.5*x*x + 1
+		case x < 2:
+			return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+		}
+		return 0
+	}},
+	ResampleLanczos: {3, func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		if math.Abs(x) >= 3 {
+			return 0
+		}
+		px := math.Pi * x
+		return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+	}},
+}
+
+// kernelFor looks up method's kernel, falling back to bilinear for unknown methods
+func kernelFor(method ResampleMethod) resampleKernel {
+	k, ok := resampleKernels[method]
+	if !ok {
+		return resampleKernels[ResampleBilinear]
+	}
+	return k
+}
+
+// weights are how much each source pixel from first onwards adds to a pixel centred on centre, adding up to 1.
+// A scale above 1 stretches the kernel so shrinking averages every source pixel instead of skipping some
+func (k resampleKernel) weights(centre, scale float64) (first int, weights []float64) {
+	support := k.support * scale
+	first = int(math.Ceil(centre - support))
+	last := int(math.Floor(centre + support))
+	var sum float64
+	for i := first; i <= last; i++ {
+		w := k.weight((float64(i) - centre) / scale)
+		weights = append(weights, w)
+		sum += w
+	}
+	if sum != 0 {
+		for i := range weights {
+			weights[i] /= sum
+		}
+	}
+	return first, weights
+}
+
+// premultiplied reads the pixel at i with its colour multiplied by its alpha, so transparent pixels don't bleed
+// their colour into their neighbours
+func premultiplied[S sample](pix []S, i int) [4]float64 {
+	a := float64(pix[i+3]) / 255
+	return [4]float64{float64(pix[i+0]) * a, float64(pix[i+1]) * a, float64(pix[i+2]) * a, float64(pix[i+3])}
+}
+
+// setPremultiplied writes a premultiplied colour back to the pixel of px at i
+func setPremultiplied[S sample](px pixels[S], i int, c [4]float64) {
+	a := Clamp(c[3], 0, 255)
+	if a > 0 {
+		for ch := range 3 {
+			px.Pix[i+ch] = px.write(float32(c[ch] * 255 / a))
+		}
+	}
+	px.Pix[i+3] = px.write(float32(a))
+}
+
+// Resize scales src to width by height with method, pixels past the edge repeat the edge pixel. It returns the
+// context's error if ctx is cancelled part way through
+func Resize(ctx context.Context, src *image.RGBA, width, height int, method ResampleMethod) (*image.RGBA, error) {
+	dst, err := resize(ctx, rgbaPixels(src), width, height, method)
+	if err != nil {
+		return nil, err
+	}
+	return dst.rgba(), nil
+}
+
+// resize is Resize for an 8-bit or linear image
+func resize[S sample](ctx context.Context, src pixels[S], width, height int, method ResampleMethod) (pixels[S], error) {
+	width, height = max(width, 1), max(height, 1)
+	k := kernelFor(method)
+	srcW, srcH := src.Width, src.Height
+	if srcW == 0 || srcH == 0 {
+		return src.resized(width, height), nil
+	}
+	// where each destination column and row comes from, nearest never blends so it's never stretched
+	axis := func(dst, src int) (firsts []int, weights [][]float64) {
+		ratio := float64(src) / float64(dst)
+		scale := max(ratio, 1)
+		if method == ResampleNearest {
+			scale = 1
+		}
+		firsts, weights = make([]int, dst), make([][]float64, dst)
+		for i := range dst {
+			firsts[i], weights[i] = k.weights((float64(i)+0.5)*ratio-0.5, scale)
+		}
+		return firsts, weights
+	}
+	xFirsts, xWeights := axis(width, srcW)
+	yFirsts, yWeights := axis(height, srcH)
+
+	// resize the rows first, then the columns of the result
+	rows := make([][4]float64, srcH*width)
+	err := ParallelRowsContext(ctx, srcH, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range width {
+				var c [4]float64
+				for j, w := range xWeights[x] {
+					p := premultiplied(src.Pix, y*src.Stride+Clamp(xFirsts[x]+j, 0, srcW-1)*4)
+					for ch := range c {
+						c[ch] += p[ch] * w
+					}
+				}
+				rows[y*width+x] = c
+			}
+		}
+	})
+	if err != nil {
+		return pixels[S]{}, err
+	}
+	dst := src.resized(width, height)
+	err = ParallelRowsContext(ctx, height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range width {
+				var c [4]float64
+				for j, w := range yWeights[y] {
+					p := rows[Clamp(yFirsts[y]+j, 0, srcH-1)*width+x]
+					for ch := range c {
+						c[ch] += p[ch] * w
+					}
+				}
+				setPremultiplied(dst, y*dst.Stride+x*4, c)
+			}
+		}
+	})
+	return dst, err
+}
+
+// RotateAngle rotates src clockwise by degrees with method. The result is made big enough to hold all of src and
+// the corners it doesn't cover are transparent. It returns the context's error if ctx is cancelled part way through
+func RotateAngle(ctx context.Context, src *image.RGBA, degrees float64, method ResampleMethod) (*image.RGBA, error) {
+	dst, err := rotateAngle(ctx, rgbaPixels(src), degrees, method)
+	if err != nil {
+		return nil, err
+	}
+	return dst.rgba(), nil
+}
+
+// rotateAngle is RotateAngle for an 8-bit or linear image
+func rotateAngle[S sample](ctx context.Context, src pixels[S], degrees float64, method ResampleMethod) (pixels[S], error) {
+	k := kernelFor(method)
+	srcW, srcH := float64(src.Width), float64(src.Height)
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	// a tiny bit is taken off so rounding errors don't add a whole row or column
+	width := max(1, int(math.Ceil(math.Abs(srcW*cos)+math.Abs(srcH*sin)-1e-6)))
+	height := max(1, int(math.Ceil(math.Abs(srcW*sin)+math.Abs(srcH*cos)-1e-6)))
+	dst := src.resized(width, height)
+	err := ParallelRowsContext(ctx, height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range width {
+				// turn the centre of the destination pixel back the other way to find where it is in src
+				dx, dy := float64(x)+0.5-float64(width)/2, float64(y)+0.5-float64(height)/2
+				sx, sy := cos*dx+sin*dy+srcW/2-0.5, -sin*dx+cos*dy+srcH/2-0.5
+				xFirst, xWeights := k.weights(sx, 1)
+				yFirst, yWeights := k.weights(sy, 1)
+				var c [4]float64
+				for j, wy := range yWeights {
+					py := yFirst + j
+					if py < 0 || py >= src.Height {
+						continue
+					}
+					for i, wx := range xWeights {
+						px := xFirst + i
+						// anything outside src is transparent so the edges fade out smoothly
+						if px < 0 || px >= src.Width {
+							continue
+						}
+						p := premultiplied(src.Pix, py*src.Stride+px*4)
+						for ch := range c {
+							c[ch] += p[ch] * wx * wy
+						}
+					}
+				}
+				setPremultiplied(dst, y*dst.Stride+x*4, c)
+			}
+		}
+	})
+	return dst, err
+}
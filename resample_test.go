@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestResample(t *testing.T) {
+	t.Run("Same size leaves the image alone", func(t *testing.T) {
+		// Aim: every kernel is 1 at its centre and 0 at every other whole pixel so nothing should change
+		img := randomImage(16, 12)
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+		for method := ResampleNearest; method <= ResampleLanczos; method++ {
+			if res, _ := Resize(context.Background(), img, 16, 12, method); !bytes.Equal(res.Pix, img.Pix) {
+				t.Errorf("Expected method %d to leave the image alone", method)
+			}
+			if res, _ := RotateAngle(context.Background(), img, 0, method); !bytes.Equal(res.Pix, img.Pix) {
+				t.Errorf("Expected method %d to leave the image alone when not rotating", method)
+			}
+		}
+	})
+	t.Run("Nearest doubles pixels", func(t *testing.T) {
+		// Aim: twice the size with nearest should give 2x2 blocks of each pixel
+		img := randomImage(8, 8)
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+		res, _ := Resize(context.Background(), img, 16, 16, ResampleNearest)
+		for y := range 16 {
+			for x := range 16 {
+				got, expected := res.Pix[y*res.Stride+x*4:y*res.Stride+x*4+4], img.Pix[y/2*img.Stride+x/2*4:y/2*img.Stride+x/2*4+4]
+				if !bytes.Equal(got, expected) {
+					t.Fatalf("Expected %d, %d to be %v, got %v", x, y, expected, got)
+				}
+			}
+		}
+	})
+	t.Run("Shrinking averages", func(t *testing.T) {
+		// Aim: a fine checkerboard shrunk a lot should turn grey rather than picking out black or white pixels
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for i := 0; i < len(img.Pix); i += 4 {
+			x, y := (i/4)%64, (i/4)/64
+			v := uint8(255 * ((x + y) % 2))
+			img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 255
+		}
+		for _, method := range []ResampleMethod{ResampleBilinear, ResampleBicubic, ResampleLanczos} {
+			res, _ := Resize(context.Background(), img, 8, 8, method)
+			for i := 0; i < len(res.Pix); i += 4 {
+				if v := res.Pix[i]; v < 117 || v > 138 {
+					t.Fatalf("Expected method %d to average to grey, got %d", method, v)
+				}
+			}
+		}
+	})
+	t.Run("Rotating a quarter turn by angle", func(t *testing.T) {
+		// Aim: 90 degrees by angle should land on the same pixels as a quarter turn
+		img := randomImage(10, 6)
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+		res, _ := RotateAngle(context.Background(), img, 90, ResampleBilinear)
+		expected := RotateQuarters(img, 1)
+		if res.Rect != expected.Rect {
+			t.Fatalf("Expected %v, got %v", expected.Rect, res.Rect)
+		}
+		for i := range res.Pix {
+			if math.Abs(float64(res.Pix[i])-float64(expected.Pix[i])) > 1 {
+				t.Fatalf("Expected byte %d to be %d, got %d", i, expected.Pix[i], res.Pix[i])
+			}
+		}
+	})
+	t.Run("Rotated corners are transparent", func(t *testing.T) {
+		// Aim: the canvas grows to fit the rotated image and the corners it doesn't cover are see through
+		img := randomImage(20, 20)
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+		res, _ := RotateAngle(context.Background(), img, 45, ResampleBicubic)
+		if res.Rect.Dx() != 29 || res.Rect.Dy() != 29 {
+			t.Errorf("Expected a 29x29 image, got %v", res.Rect)
+		}
+		if res.Pix[3] != 0 || res.Pix[(14*res.Stride)+14*4+3] != 255 {
+			t.Errorf("Expected a transparent corner and an opaque centre, got %d and %d", res.Pix[3], res.Pix[(14*res.Stride)+14*4+3])
+		}
+	})
+}
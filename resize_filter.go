@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// ResizeMode picks how the new size is given
+type ResizeMode int
+
+const (
+	ResizeFactor ResizeMode = iota // a factor of the width and height
+	ResizeSize                     // a width and height in pixels of the original image
+)
+
+// Scales the image, by a factor or to a size in pixels. Pixels are of the original image so the shrunk preview is
+// scaled to match the full resolution export
+type ResizeFilter struct{}
+
+func (ResizeFilter) Name() string {
+	return "control.resize"
+}
+func (ResizeFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.resize.mode", Kind: ParamChoice, Min: 0, Max: float64(ResizeSize), Default: float64(ResizeFactor), Options: []string{"control.resize.factor", "control.resize.size"}},
+		{Key: "control.resize.width", Kind: ParamFloat, Min: 0.05, Max: 4, Default: 1},
+		{Key: "control.resize.height", Kind: ParamFloat, Min: 0.05, Max: 4, Default: 1},
+		// a target width or height of 0 keeps the aspect ratio, both 0 leaves the size alone
+		{Key: "control.resize.targetwidth", Kind: ParamInt, Min: 0, Max: MaxImageSize, Default: 0},
+		{Key: "control.resize.targetheight", Kind: ParamInt, Min: 0, Max: MaxImageSize, Default: 0},
+		{Key: "control.resize.resample", Kind: ParamChoice, Min: 0, Max: float64(ResampleLanczos), Default: float64(ResampleLanczos), Options: ResampleOptions},
+		linearLightParam,
+		sourceScaleParam,
+	}
+}
+func (f ResizeFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f ResizeFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	resized, err := resizeBy(ctx, rgbaPixels(img), p)
+	if err != nil {
+		return err
+	}
+	*img = *resized.rgba()
+	return nil
+}
+func (ResizeFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	resized, err := resizeBy(ctx, img.pixels(), f.Params)
+	if err != nil {
+		return err
+	}
+	*img = *resized.linearImage()
+	return nil
+}
+
+// resizeBy scales px to the size in p, px is given back as it is when that's the size it already is
+func resizeBy[S sample](ctx context.Context, px pixels[S], p Params) (pixels[S], error) {
+	width, height := ResizedSize(px.Width, px.Height, p)
+	if width == px.Width && height == px.Height {
+		return px, nil
+	}
+	return resize(ctx, px, width, height, ResampleMethod(p.Int("control.resize.resample")))
+}
+
+// ResizedSize is the size the resize filter with the settings in p turns a width by height image into
+func ResizedSize(width, height int, p Params) (int, int) {
+	if ResizeMode(p.Int("control.resize.mode")) == ResizeFactor {
+		return int(math.Round(float64(width) * p.Float("control.resize.width"))), int(math.Round(float64(height) * p.Float("control.resize.height")))
+	}
+	targetWidth, targetHeight := p.Int("control.resize.targetwidth"), p.Int("control.resize.targetheight")
+	switch {
+	case targetWidth > 0 && targetHeight > 0:
+		return max(1, sourcePixels(p, targetWidth)), max(1, sourcePixels(p, targetHeight))
+	case targetWidth > 0:
+		w := max(1, sourcePixels(p, targetWidth))
+		return w, max(1, int(math.Round(float64(height*w)/float64(max(width, 1)))))
+	case targetHeight > 0:
+		h := max(1, sourcePixels(p, targetHeight))
+		return max(1, int(math.Round(float64(width*h)/float64(max(height, 1))))), h
+	}
+	return width, height
+}
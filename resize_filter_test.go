@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestResizeFilter(t *testing.T) {
+	size := func(p Params) (int, int) {
+		img := randomImage(40, 20)
+		ResizeFilter{}.Apply(img, p)
+		return img.Rect.Dx(), img.Rect.Dy()
+	}
+	t.Run("Resize by factor", func(t *testing.T) {
+		p := NewFilters().Params
+		p["control.resize.width"], p["control.resize.height"] = 0.5, 2
+		if w, h := size(p); w != 20 || h != 40 {
+			t.Errorf("Expected 20x40, got %dx%d", w, h)
+		}
+	})
+	t.Run("Resize to a size", func(t *testing.T) {
+		// Aim: both sizes given should be used as they are, one size given should keep the aspect ratio and neither
+		// should leave the image alone
+		for _, tt := range []struct {
+			width, height                 float64
+			expectedWidth, expectedHeight int
+		}{
+			{80, 60, 80, 60},
+			{80, 0, 80, 40},
+			{0, 5, 10, 5},
+			{0, 0, 40, 20},
+		} {
+			p := NewFilters().Params
+			p["control.resize.mode"] = float64(ResizeSize)
+			p["control.resize.targetwidth"], p["control.resize.targetheight"] = tt.width, tt.height
+			if w, h := size(p); w != tt.expectedWidth || h != tt.expectedHeight {
+				t.Errorf("%vx%v: expected %dx%d, got %dx%d", tt.width, tt.height, tt.expectedWidth, tt.expectedHeight, w, h)
+			}
+		}
+	})
+	t.Run("Sizes are of the original image", func(t *testing.T) {
+		// Aim: on a preview a quarter of the size, 800x600 should come out a quarter of that
+		p := NewFilters().Params
+		p["control.resize.mode"] = float64(ResizeSize)
+		p["control.resize.targetwidth"], p["control.resize.targetheight"] = 800, 600
+		p[SourceScaleKey] = 4
+		if w, h := size(p); w != 200 || h != 150 {
+			t.Errorf("Expected 200x150, got %dx%d", w, h)
+		}
+	})
+}
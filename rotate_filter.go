@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"image"
+)
+
+// Rotates by quarter turns, which only moves pixels around, then by any angle in between
+type RotateFilter struct{}
+
+func (RotateFilter) Name() string {
+	return "control.rotate"
+}
+func (RotateFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.rotate.quarter", Kind: ParamChoice, Min: 0, Max: 3, Default: 0, Options: []string{
+			"control.rotate.0",
+			"control.rotate.90",
+			"control.rotate.180",
+			"control.rotate.270",
+		}},
+		{Key: "control.rotate.angle", Kind: ParamFloat, Min: -45, Max: 45, Default: 0},
+		{Key: "control.rotate.resample", Kind: ParamChoice, Min: 0, Max: float64(ResampleLanczos), Default: float64(ResampleBicubic), Options: ResampleOptions},
+		linearLightParam,
+	}
+}
+func (f RotateFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f RotateFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	rotated, err := rotate(ctx, rgbaPixels(img), p)
+	if err != nil {
+		return err
+	}
+	*img = *rotated.rgba()
+	return nil
+}
+func (RotateFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	rotated, err := rotate(ctx, img.pixels(), f.Params)
+	if err != nil {
+		return err
+	}
+	*img = *rotated.linearImage()
+	return nil
+}
+
+func rotate[S sample](ctx context.Context, px pixels[S], p Params) (pixels[S], error) {
+	rotated := rotateQuarters(px, p.Int("control.rotate.quarter"))
+	if angle := p.Float("control.rotate.angle"); angle != 0 {
+		return rotateAngle(ctx, rotated, angle, ResampleMethod(p.Int("control.rotate.resample")))
+	}
+	return rotated, nil
+}
+
+// RotateQuarters gives a copy of img turned clockwise by quarters quarter turns
+func RotateQuarters(img *image.RGBA, quarters int) *image.RGBA {
+	return rotateQuarters(rgbaPixels(img), quarters).rgba()
+}
+
+// rotateQuarters is RotateQuarters for an 8-bit or linear image
+func rotateQuarters[S sample](img pixels[S], quarters int) pixels[S] {
+	quarters = (quarters%4 + 4) % 4
+	w, h := img.Width, img.Height
+	if quarters%2 == 1 {
+		w, h = h, w
+	}
+	dst := img.resized(w, h)
+	srcW, srcH := img.Width, img.Height
+	ParallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range w {
+				// the source pixel that ends up at x, y
+				sx, sy := x, y
+				switch quarters {
+				case 1:
+					sx, sy = y, srcH-1-x
+				case 2:
+					sx, sy = srcW-1-x, srcH-1-y
+				case 3:
+					sx, sy = srcW-1-y, x
+				}
+				copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], img.Pix[sy*img.Stride+sx*4:])
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestRotateFilter(t *testing.T) {
+	t.Run("A quarter turn is clockwise", func(t *testing.T) {
+		// Aim: the top left pixel should end up top right and the size should swap
+		img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+		img.Pix[3] = 255
+		p := NewFilters().Params
+		p["control.rotate.quarter"] = 1
+		RotateFilter{}.Apply(img, p)
+		if img.Rect.Dx() != 2 || img.Rect.Dy() != 3 {
+			t.Fatalf("Expected a 2x3 image, got %v", img.Rect)
+		}
+		if img.Pix[1*4+3] != 255 {
+			t.Errorf("Expected the top left pixel to move to the top right, got %v", img.Pix)
+		}
+	})
+	t.Run("Four quarter turns", func(t *testing.T) {
+		// Aim: quarter turns only move pixels so going all the way round gives back exactly the same image
+		img := randomImage(7, 5)
+		res := img
+		for range 4 {
+			res = RotateQuarters(res, 1)
+		}
+		if res.Rect != img.Rect || !bytes.Equal(res.Pix, img.Pix) {
+			t.Error("Expected four quarter turns to give back the same image")
+		}
+		if half := RotateQuarters(RotateQuarters(img, 1), 1); !bytes.Equal(half.Pix, RotateQuarters(img, 2).Pix) {
+			t.Error("Expected two quarter turns to be a half turn")
+		}
+		if back := RotateQuarters(RotateQuarters(img, 1), 3); !bytes.Equal(back.Pix, img.Pix) {
+			t.Error("Expected three quarter turns to undo one")
+		}
+	})
+	t.Run("Defaults leave the image alone", func(t *testing.T) {
+		img := randomImage(8, 8)
+		expected := ToRGBA(img)
+		RotateFilter{}.Apply(img, NewFilters().Params)
+		if !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Default rotation changed the image")
+		}
+	})
+}
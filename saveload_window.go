@@ -12,6 +12,11 @@ type SaveLoadWindow struct {
 	Anchor                   rl.Vector2
 	InteractedWith           time.Time
 	IsFileTypeDropDownActive bool
+	ProjectPath              string
+	IsProjectPathEditing     bool
+	EmbedImage               bool
+	// Result of the last project save or load, shown at the bottom of the window
+	Status string
 }
 
 func (s *SaveLoadWindow) getRect() rl.Rectangle {
@@ -26,8 +31,39 @@ func (s *SaveLoadWindow) Draw() {
 		"Save file") {
 		state.SaveImage()
 	}
-	
-	// File type dropdown
+
+	// Project file name
+	gui.Label(rl.NewRectangle(s.getRect().X+10, s.getRect().Y+30+85, s.getRect().Width-20, 20), Translate("window.save.project"))
+	if gui.TextBox(rl.NewRectangle(s.getRect().X+10, s.getRect().Y+30+105, s.getRect().Width-20, 30), &s.ProjectPath, 256, s.IsProjectPathEditing) {
+		s.IsProjectPathEditing = !s.IsProjectPathEditing
+	}
+	// Embed image checkbox
+	s.EmbedImage = gui.CheckBox(
+		rl.NewRectangle(s.getRect().X+10, s.getRect().Y+30+145, 10, 10),
+		Translate("window.save.embed"),
+		s.EmbedImage,
+	)
+	// Save project button
+	if gui.Button(rl.NewRectangle(s.getRect().X+10, s.getRect().Y+30+165, (s.getRect().Width-30)/2, 30), Translate("window.save.saveproject")) {
+		if err := state.SaveProject(s.ProjectPath, s.EmbedImage); err != nil {
+			ErrorLogf("Couldn't save project: %v", err.Error())
+			s.Status = err.Error()
+		} else {
+			s.Status = Translate("window.save.saved")
+		}
+	}
+	// Load project button
+	if gui.Button(rl.NewRectangle(s.getRect().X+20+(s.getRect().Width-30)/2, s.getRect().Y+30+165, (s.getRect().Width-30)/2, 30), Translate("window.save.loadproject")) {
+		if err := state.LoadProject(s.ProjectPath); err != nil {
+			ErrorLogf("Couldn't load project: %v", err.Error())
+			s.Status = err.Error()
+		} else {
+			s.Status = Translate("window.save.loaded")
+		}
+	}
+	gui.Label(rl.NewRectangle(s.getRect().X+10, s.getRect().Y+30+205, s.getRect().Width-20, 20), s.Status)
+
+	// File type dropdown, drawn last so it goes over the controls below it
 	if gui.DropdownBox(
 		rl.NewRectangle(s.getRect().X+10, s.getRect().Y+30+45, s.getRect().Width-20, 30),
 		"png;jpg;tiff;bmp",
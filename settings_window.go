@@ -31,7 +31,7 @@ func (w *SettingsWindow) Draw() {
 
 	storeFontSize := state.Config.FontSize
 	// font size slider
-	state.Config.FontSize = int64(gui.Slider(rl.NewRectangle(w.Anchor.X+70, w.Anchor.Y+90, 100, 7), "10", "42", float32(state.Config.FontSize), 10.0, 42.0))
+	state.Config.FontSize = int64(gui.Slider(rl.NewRectangle(w.Anchor.X+70, w.Anchor.Y+90, 100, 7), "10", "42", float32(state.Config.FontSize), MinFontSize, MaxFontSize))
 	
 	// set the font size if it changes
 	if state.Config.FontSize != storeFontSize {
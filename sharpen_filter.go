@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"image"
+)
+
+// SharpenMode picks how the blurred copy that gets subtracted is made
+type SharpenMode int
+
+const (
+	SharpenUnsharpMask SharpenMode = iota // subtract a gaussian blur of the given radius
+	SharpenSimple                         // the classic 3x3 sharpen kernel
+)
+
+type SharpenFilter struct{}
+
+func (SharpenFilter) Name() string {
+	return "control.sharpen"
+}
+func (SharpenFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.sharpen.mode", Kind: ParamChoice, Min: 0, Max: 1, Default: float64(SharpenUnsharpMask), Options: []string{"control.sharpen.unsharpmask", "control.sharpen.simple"}},
+		{Key: "control.sharpen.amount", Kind: ParamFloat, Min: 0, Max: 5, Default: 1},
+		{Key: "control.sharpen.radius", Kind: ParamInt, Min: 1, Max: 20, Default: 2},
+		{Key: "control.sharpen.threshold", Kind: ParamInt, Min: 0, Max: 255, Default: 0},
+		linearLightParam,
+		pixelScaleParam,
+	}
+}
+func (f SharpenFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (f SharpenFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	if LinearLight(p) {
+		return applyLinear(ctx, f, img, Filters{Params: p})
+	}
+	return sharpen(ctx, rgbaPixels(img), p)
+}
+func (SharpenFilter) ApplyLinear(ctx context.Context, img *LinearImage, f Filters) error {
+	return sharpen(ctx, img.pixels(), f.Params)
+}
+
+func sharpen[S sample](ctx context.Context, px pixels[S], p Params) error {
+	w, h := px.Width, px.Height
+	amount := float32(p.Float("control.sharpen.amount"))
+	threshold := float32(p.Int("control.sharpen.threshold"))
+
+	// read from a copy so the bands never see pixels another band has already sharpened
+	src := px.clone()
+	var blurred []S
+	if SharpenMode(p.Int("control.sharpen.mode")) == SharpenUnsharpMask {
+		radius := scalePixels(p, p.Int("control.sharpen.radius"))
+		blur := px.clone()
+		kernel := GaussianKernel(radius, float64(radius)/2)
+		if err := convolveSeparable(ctx, blur, kernel, kernel, EdgeClamp); err != nil {
+			return err
+		}
+		blurred = blur.Pix
+	}
+
+	// the simple kernel's neighbours are a preview pixel away
+	spacing := scalePixels(p, 1)
+	return ParallelRowsContext(ctx, h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				i := y*px.Stride + x*4
+				// alpha is left alone so edges don't get haloes of transparency
+				for c := range 3 {
+					orig := float32(src.Pix[i+c])
+					// detail is what the blur took away, adding more of it back sharpens
+					var detail float32
+					if blurred != nil {
+						detail = orig - float32(blurred[i+c])
+					} else {
+						// 4 times the pixel minus its 4 neighbours, which with an amount of 1 is the usual
+						// [0 -1 0; -1 5 -1; 0 -1 0] kernel
+						detail = 4 * orig
+						for _, d := range [4][2]int{{0, -1}, {-1, 0}, {1, 0}, {0, 1}} {
+							j := edgeIndex(y+d[1]*spacing, h, EdgeClamp)*px.Stride + edgeIndex(x+d[0]*spacing, w, EdgeClamp)*4
+							detail -= float32(src.Pix[j+c])
+						}
+					}
+					// small differences are probably noise so they're left as they are, in linear light the threshold is
+					// compared against the linear difference
+					if detail < threshold && -detail < threshold {
+						continue
+					}
+					px.Pix[i+c] = px.write(orig + amount*detail)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSharpenFilter(t *testing.T) {
+	params := func(mode SharpenMode, threshold int) Params {
+		return Params{
+			"control.sharpen.mode":      float64(mode),
+			"control.sharpen.amount":    1,
+			"control.sharpen.radius":    2,
+			"control.sharpen.threshold": float64(threshold),
+		}
+	}
+	// a vertical edge from dark grey to light grey half way across
+	step := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 10, 4))
+		for y := range 4 {
+			for x := range 10 {
+				v := uint8(80)
+				if x >= 5 {
+					v = 160
+				}
+				img.Set(x, y, color.RGBA{v, v, v, 255})
+			}
+		}
+		return img
+	}
+	for name, mode := range map[string]SharpenMode{"unsharp mask": SharpenUnsharpMask, "3x3": SharpenSimple} {
+		t.Run("Edge contrast increases with "+name, func(t *testing.T) {
+			// Aim: either side of the edge should be pushed further apart, and flat areas left alone
+			img := step()
+			SharpenFilter{}.Apply(img, params(mode, 0))
+			if dark, light := img.RGBAAt(4, 2).R, img.RGBAAt(5, 2).R; dark >= 80 || light <= 160 {
+				t.Errorf("Mode %d: expected the edge to be sharpened, got %d and %d", mode, dark, light)
+			}
+			if flat := img.RGBAAt(0, 2).R; flat != 80 {
+				t.Errorf("Mode %d: flat area changed to %d", mode, flat)
+			}
+			if alpha := img.RGBAAt(5, 2).A; alpha != 255 {
+				t.Errorf("Mode %d: alpha changed to %d", mode, alpha)
+			}
+		})
+		t.Run("Threshold with "+name, func(t *testing.T) {
+			// Aim: differences below the threshold should be left alone
+			img := step()
+			SharpenFilter{}.Apply(img, params(mode, 255))
+			if !bytes.Equal(img.Pix, step().Pix) {
+				t.Errorf("Mode %d: image changed despite the threshold", mode)
+			}
+		})
+	}
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
+	"unsafe"
 
 	gui "github.com/gen2brain/raylib-go/raygui"
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -17,8 +21,6 @@ import (
 	"golang.org/x/image/tiff"
 )
 
-const FilterCount = 6
-
 const (
 	English       Language = iota
 	German                 = iota
@@ -31,17 +33,25 @@ type State struct {
 	ImagePath   string
 
 	// We have current image which never changes and shown image is the one that is shown on the screen and edited
-	OrigImage    image.RGBA // NOTE: making this a pointer caused a big pass by reference / pass by value bug meaning that filters couldn't be unapplied'
+	OrigImage image.RGBA // NOTE: making this a pointer caused a big pass by reference / pass by value bug meaning that filters couldn't be unapplied'
+	// The original is kept at full resolution for exporting, the preview is a copy shrunk to fit the screen that the filters are previewed on
+	PreviewImage image.RGBA
 	WorkingImage image.RGBA
 	ShownImage   *rl.Image
 	ImagePalette []rl.Color
+	// Fraction of WorkingImage closest to each colour of ImagePalette
+	PaletteShares []float64
 	
 	// Window data
-	FilterWindow   FilterOrderWindow
-	PaletteWindow  PaletteWindow
-	HelpWindow     HelpWindow
-	SaveLoadWindow SaveLoadWindow
-	SettingsWindow SettingsWindow
+	FilterWindow      FilterOrderWindow
+	PaletteWindow     PaletteWindow
+	HelpWindow        HelpWindow
+	SaveLoadWindow    SaveLoadWindow
+	SettingsWindow    SettingsWindow
+	HistoryWindow     HistoryWindow
+	PresetsWindow     PresetsWindow
+	ConvolutionWindow ConvolutionWindow
+	CurvesWindow      CurvesWindow
 	
 	// Histogram data
 	RedHistogram   [256]int
@@ -54,51 +64,28 @@ type State struct {
 
 	// UI
 	Filters Filters
+	// How far the filter controls down the side have been scrolled
+	SidebarScroll rl.Vector2
+	// Applies the filters in the background
+	Renderer Renderer
+	// Undo/redo stack of the filters
+	History History
+	// Named filter setups
+	Presets PresetLibrary
 	
 	// Config data
 	Config       Config
+	SavedConfig  Config // what's in the settings file, so changes can be spotted and saved
 	LanguageData [LanguageCount]map[string]string
 }
-// filters object
-type Filters struct {
-	IsGrayscaleEnabled bool
-
-	IsQuantizingEnabled bool
-	QuantizingBands     uint8
-
-	IsDitheringEnabled           bool
-	DitheringQuantizationBuckets uint8
-
-	ChannelAdjustmentEnabled bool
-	ChannelAdjustment        [3]float32
-
-	IsBoxBlurEnabled  bool
-	BoxBlurIterations int
-
-	LightenDarken float64
-
-	Order [FilterCount]string
-}
-
 type ColourHistogram struct {
 	RedChannel   map[uint8]int
 	GreenChannel map[uint8]int
 	BlueChannel  map[uint8]int
 }
 
-func (s *State) LightenDarken() {
-	// +1 makes everything white
-	// -1 makes everything black
-	// 0 does nothing
-	for i := 0; i < len(s.WorkingImage.Pix); i += 4 {
-		s.WorkingImage.Pix[i+0] = uint8(Clamp(float64(s.WorkingImage.Pix[i+0])*(s.Filters.LightenDarken+1), 0, 255))
-		s.WorkingImage.Pix[i+1] = uint8(Clamp(float64(s.WorkingImage.Pix[i+1])*(s.Filters.LightenDarken+1), 0, 255))
-		s.WorkingImage.Pix[i+2] = uint8(Clamp(float64(s.WorkingImage.Pix[i+2])*(s.Filters.LightenDarken+1), 0, 255))
-	}
-}
-
 func (s *State) GetFiltersListViewString() string {
-	return strings.Join(MapOut(s.Filters.Order[:], Translate), ";")
+	return strings.Join(MapOut(s.Filters.Order, Translate), ";")
 }
 
 func (s *State) GenerateHistogram() {
@@ -123,96 +110,183 @@ func Clamp[T interface{ ~int | ~float64 }](v, min, max T) T {
 	return v
 }
 
-func (s *State) BoxBlurFilter() {
-	bounds := s.WorkingImage.Bounds()
-	w, h := bounds.Dx(), bounds.Dy()
-	// for each iteration
-	for i := range s.Filters.BoxBlurIterations {
-		_ = i
-		// for each pixel
-		for y := 0; y < h; y++ {
-			for x := 0; x < w; x++ {
-				// if we're on an edge, skip
-				if x < 1 || y < 1 || x+1 == w || y+1 == h {
-					continue
-				}
-				// get the 3x3 surrounding pixels
-				pixels := make([]color.Color, 9)
-				pixels[0] = s.WorkingImage.At(x-1, y-1) // top left
-				pixels[1] = s.WorkingImage.At(x, y-1)   // top middle
-				pixels[2] = s.WorkingImage.At(x+1, y-1) // top right
-
-				pixels[3] = s.WorkingImage.At(x-1, y) // middle left
-				pixels[4] = s.WorkingImage.At(x, y)   // centre
-				pixels[5] = s.WorkingImage.At(x+1, y) // middle right
-
-				pixels[6] = s.WorkingImage.At(x-1, y+1)// bottom left  
-				pixels[7] = s.WorkingImage.At(x, y+1)  // bottom middle
-				pixels[8] = s.WorkingImage.At(x+1, y+1)// bottom right 
-					
-				// in the 3x3 kernel get the mean of red green and blue
-				var rSum, gSum, bSum, aSum int
-				for _, p := range pixels {
-					r, g, b, a := p.RGBA()
-					rSum += int(r >> 8)
-					gSum += int(g >> 8)
-					bSum += int(b >> 8)
-					aSum += int(a >> 8)
-				}
-				rSum /= 9
-				gSum /= 9
-				bSum /= 9
-				aSum /= 9
-				// set the pixel to the mean of the surrounding pixels and itself
-				s.WorkingImage.Set(x, y, color.RGBA{R: uint8(rSum), G: uint8(gSum), B: uint8(bSum), A: uint8(aSum)})
-			}
-		}
+// RefreshImage starts rendering the preview with the current filters in the background, ReceiveRender picks up the result
+func (s *State) RefreshImage() {
+	InfoLog("Applying filters")
+	DebugLogf("Current filters: %+v", s.Filters) // %+v prints a struct with field names
+	// the palette lives in the filters so undo, presets and projects bring it back too
+	s.ImagePalette = s.Filters.Palette
+	s.Renderer.Start(&s.PreviewImage, s.PreviewFilters())
+}
+
+// PreviewFilters is a copy of the filters for rendering the preview, with sizes in pixels of the original image
+// scaled down to the preview
+func (s *State) PreviewFilters() Filters {
+	filters := s.Filters.Clone()
+	if w := s.PreviewImage.Rect.Dx(); w > 0 && s.OrigImage.Rect.Dx() > 0 {
+		filters.Params[SourceScaleKey] = float64(s.OrigImage.Rect.Dx()) / float64(w)
 	}
+	return filters
 }
 
-func (s *State) RefreshImage() {
-	// CONSTRUCT IMAGE PALETTE MAP
-	s.ApplyFilters()                                             // up to 145ms
-	s.CurrentTexture = rl.LoadTextureFromImage(state.ShownImage) // >1ms
-	s.GenerateHistogram()
+// SetPalette changes the palette that palette dithering uses
+func (s *State) SetPalette(palette []rl.Color) {
+	// anything past MaxPaletteSize is dropped
+	s.Filters.Palette = slices.Clone(palette[:min(len(palette), MaxPaletteSize)])
+	s.ImagePalette = s.Filters.Palette
+	s.PaletteShares = PaletteShares(&s.WorkingImage, s.ImagePalette)
+	s.History.Record(s.Filters)
 }
 
-func (s *State) LoadImageFile(path string) {
-	// get the extension and match it, if there's an error in this we just return without settings ImageLoaded to true
-	fileParts := strings.Split(path, ".")
-	extension := strings.ToLower(fileParts[len(fileParts)-1])
-	f, err := os.Open(s.ImagePath)
+// UsePalette changes the palette and turns palette dithering on
+func (s *State) UsePalette(palette []rl.Color) {
+	s.Filters.Enabled[PaletteDitheringFilter{}.Name()] = true
+	s.SetPalette(palette)
+}
+
+// ExtractPalette replaces the palette with the n dominant colours of the filtered image
+func (s *State) ExtractPalette(method ExtractionMethod, n int) {
+	entries := ExtractPalette(&s.WorkingImage, method, n)
+	InfoLogf("Extracted %d colours", len(entries))
+	palette := make([]rl.Color, len(entries))
+	for i, e := range entries {
+		palette[i] = e.Colour
+	}
+	s.SetPalette(palette)
+}
+
+// ImportPalette loads a palette file and uses it for palette dithering
+func (s *State) ImportPalette(path string) error {
+	palette, err := LoadPaletteFile(path)
 	if err != nil {
-		return
+		return err
+	}
+	InfoLogf("Imported %d colours from %v", len(palette), path)
+	s.UsePalette(palette)
+	return nil
+}
+
+// ExportPalette saves the current palette in the format matching path's extension
+func (s *State) ExportPalette(path string) error {
+	if len(s.ImagePalette) == 0 {
+		return errors.New("there is no palette to export")
 	}
+	return SavePaletteFile(path, s.ImagePalette)
+}
 
-	var image image.Image
+// LoadDroppedFile opens a file dropped onto the window as a project, a palette or an image
+func (s *State) LoadDroppedFile(path string) {
+	switch {
+	case IsProjectFile(path):
+		if err := s.LoadProject(path); err != nil {
+			ErrorLogf("Couldn't load project: %v", err.Error())
+		}
+	case IsPaletteFile(path):
+		s.PaletteWindow.Showing = true
+		s.PaletteWindow.InteractedWith = time.Now()
+		s.PaletteWindow.FilePath = path
+		s.PaletteWindow.report(s.ImportPalette(path), "window.palette.imported")
+	default:
+		s.ImagePath = path
+		s.LoadImageFile(path)
+	}
+}
 
-	if extension == "jpg" || extension == "jpeg" {
-		image, err = jpeg.Decode(f)
+// ReceiveRender uploads the latest finished render to the GPU, it's called every frame and does nothing until a render finishes
+func (s *State) ReceiveRender() {
+	img, ok := s.Renderer.Poll()
+	if !ok {
+		return
 	}
-	if extension == "png" {
-		image, err = png.Decode(f)
+	// renders of a previous image are thrown away by the renderer, so the only reason for nothing is a bad stage
+	if len(img.Pix) == 0 {
+		return
+	}
+	s.WorkingImage = *img
+	if int32(img.Rect.Dx()) != s.CurrentTexture.Width || int32(img.Rect.Dy()) != s.CurrentTexture.Height {
+		// cropping, rotating or resizing changed the size so the texture has to be made again
+		rl.UnloadTexture(s.CurrentTexture)
+		// the texture has its own copy of the pixels so the raylib image can go straight away
+		resized := rl.NewImageFromImage(img)
+		s.CurrentTexture = rl.LoadTextureFromImage(resized)
+		rl.UnloadImage(resized)
+	} else {
+		// the texture is already the right size so just replace its pixels
+		rl.UpdateTexture(s.CurrentTexture, unsafe.Slice((*color.RGBA)(unsafe.Pointer(&img.Pix[0])), len(img.Pix)/4)) // >1ms
+	}
+	s.GenerateHistogram()
+	s.PaletteShares = PaletteShares(&s.WorkingImage, s.ImagePalette)
+}
+
+// Undo the last change to the filters
+func (s *State) Undo() {
+	if f, ok := s.History.Undo(); ok {
+		s.Filters = f
+	} else {
+		DebugLog("Nothing to undo")
 	}
-	if extension == "tiff" {
-		image, err = tiff.Decode(f)
+}
+
+// Redo the last undone change to the filters
+func (s *State) Redo() {
+	if f, ok := s.History.Redo(); ok {
+		s.Filters = f
+	} else {
+		DebugLog("Nothing to redo")
 	}
-	if extension == "bmp" {
-		image, err = bmp.Decode(f)
+}
+
+// JumpToHistory sets the filters back to any earlier or later point in the history
+func (s *State) JumpToHistory(i int) {
+	if f, ok := s.History.JumpTo(i); ok {
+		s.Filters = f
 	}
+}
 
+func (s *State) LoadImageFile(path string) {
+	// if there's an error in this we just return without settings ImageLoaded to true
+	image, err := DecodeImageFile(path)
 	if err != nil {
+		ErrorLogf("Couldn't load image: %v", err.Error())
 		return
 	}
 	s.LoadImage(image)
 	s.ImageLoaded = true
+	// the filters might already be set up from a previous image
+	s.RefreshImage()
+}
+
+// DecodeImageFile reads the image at path, choosing the decoder from the file extension
+func DecodeImageFile(path string) (image.Image, error) {
+	// get the extension and match it
+	fileParts := strings.Split(path, ".")
+	extension := strings.ToLower(fileParts[len(fileParts)-1])
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch extension {
+	case "jpg", "jpeg":
+		return jpeg.Decode(f)
+	case "png":
+		return png.Decode(f)
+	case "tiff":
+		return tiff.Decode(f)
+	case "bmp":
+		return bmp.Decode(f)
+	}
+	return nil, fmt.Errorf("unsupported image extension %q", extension)
 }
 
 func (s *State) LoadImage(img image.Image) {
 	DebugLog("Loading image")
-	// load the image from the file
+	// keep the image at full resolution as the source of truth
+	s.OrigImage = *ToRGBA(img)
 
-	s.ShownImage = rl.NewImageFromImage(img)
+	// make a preview that fits on the screen for showing the image
+	s.ShownImage = rl.NewImageFromImage(&s.OrigImage)
 	aspectRatio := float32(s.ShownImage.Width) / float32(s.ShownImage.Height)
 
 	// if it's longer on the x axis
@@ -222,134 +296,31 @@ func (s *State) LoadImage(img image.Image) {
 		// it's longer on the y axis
 		rl.ImageResizeNN(s.ShownImage, int32(float32(rl.GetScreenHeight())*aspectRatio), int32(rl.GetScreenHeight()))
 	}
-	s.OrigImage = *s.ShownImage.ToImage().(*image.RGBA)
-	s.WorkingImage = s.OrigImage
+	s.PreviewImage = *s.ShownImage.ToImage().(*image.RGBA)
+	s.WorkingImage = s.PreviewImage
+	rl.UnloadTexture(s.CurrentTexture)
 	s.CurrentTexture = rl.LoadTextureFromImage(s.ShownImage)
-	rl.SetWindowSize(int(state.ShownImage.Width+400), int(state.ShownImage.Height))
-}
-
-func (s *State) GrayscaleFilter() {
-	DebugLog("Grayscale filter applied")
-	// for each pixel 
-	for i := 0; i < len(s.WorkingImage.Pix); i += 4 {
-		// set the r, g and b to the mean value of r, g and b
-		mean := uint8((int(s.WorkingImage.Pix[i]) + int(s.WorkingImage.Pix[i+1]) + int(s.WorkingImage.Pix[i+2])) / 3)
-		s.WorkingImage.Pix[i+0] = mean
-		s.WorkingImage.Pix[i+1] = mean
-		s.WorkingImage.Pix[i+2] = mean
-	}
-}
-
-func (s *State) QuantizingFilter() {
-	DebugLog("Quantizing filter applied")
-
-	// floor(x/bandWidth)*bandWidth + bandWidth/2
-	// FIXME this is terrible, doesn't work and crashes in weird edge cases
-	for i := 0; i < len(s.WorkingImage.Pix); i += 4 {
-		s.WorkingImage.Pix[i+0] = Quantize(state.Filters.QuantizingBands, s.WorkingImage.Pix[i+0])
-		s.WorkingImage.Pix[i+1] = Quantize(state.Filters.QuantizingBands, s.WorkingImage.Pix[i+1])
-		s.WorkingImage.Pix[i+2] = Quantize(state.Filters.QuantizingBands, s.WorkingImage.Pix[i+2])
-		s.WorkingImage.Pix[i+3] = s.OrigImage.Pix[i+3] // NOTE: forgot this line and image went invisible, write that down
-	}
-}
-
-//func (s *State) DitheringFilter() {
-//	for y := range s.WorkingImage.Bounds().Dy() {
-//		for x := range s.WorkingImage.Bounds().Dx() {
-//			oldPixel := s.WorkingImage.At(x, y)
-//			newPixel := findClosestPalleteCol(oldPixel)
-//			idx := s.WorkingImage.PixOffset(x, y)
-//			s.WorkingImage.Pix[idx+0] = newPixel.R
-//			s.WorkingImage.Pix[idx+1] = newPixel.G
-//			s.WorkingImage.Pix[idx+2] = newPixel.B
-//			s.WorkingImage.Pix[idx+3] = newPixel.A
-//			quant_error := subCol(inlineRGBACast(oldPixel.RGBA()), inlineRGBACast(newPixel.RGBA()))
-//			// distribute the error to the neighbouring pixels
-//			s.WorkingImage.At(x+1, y)
-//
-//		}
-//
-//	}
-//}
-
-func (s *State) TintFilter() {
-	DebugLog("Tint filter applied")
-	for i := 0; i < len(s.WorkingImage.Pix); i += 4 {
-		s.WorkingImage.Pix[i+0] = uint8(float32(s.WorkingImage.Pix[i+0]) * s.Filters.ChannelAdjustment[0])
-		s.WorkingImage.Pix[i+1] = uint8(float32(s.WorkingImage.Pix[i+1]) * s.Filters.ChannelAdjustment[1])
-		s.WorkingImage.Pix[i+2] = uint8(float32(s.WorkingImage.Pix[i+2]) * s.Filters.ChannelAdjustment[2])
-	}
-}
-
-
-// Floyd-Steinburg dithering
-func (s *State) DitheringFilter() {
-	bounds := s.WorkingImage.Bounds()
-	for y := 0; y < bounds.Dy(); y++ {
-		for x := 0; x < bounds.Dx(); x++ {
-			oldR, oldG, oldB, _ := s.WorkingImage.At(x, y).RGBA()
-			newR := Quantize(s.Filters.DitheringQuantizationBuckets, uint8(oldR))
-			newG := Quantize(s.Filters.DitheringQuantizationBuckets, uint8(oldG))
-			newB := Quantize(s.Filters.DitheringQuantizationBuckets, uint8(oldB))
-			s.WorkingImage.SetRGBA(x, y, color.RGBA{R: newR, G: newG, B: newB, A: 255})
-
-			errR := uint8(oldR) - newR
-			errG := uint8(oldG) - newG
-			errB := uint8(oldB) - newB
-			distributeError(&s.WorkingImage, x+1, y+0, errR, errG, errB, 7.0/16.0) // direct right
-			distributeError(&s.WorkingImage, x-1, y+1, errR, errG, errB, 3.0/16.0) // top left
-			distributeError(&s.WorkingImage, x+0, y+1, errR, errG, errB, 5.0/16.0) // top
-			distributeError(&s.WorkingImage, x+1, y+1, errR, errG, errB, 1.0/16.0) // bottom right
-		}
-	}
+	rl.SetWindowSize(int(state.ShownImage.Width+sidebarWidth), int(state.ShownImage.Height))
 }
 
-func (s *State) ApplyFilters() {
-	InfoLog("Applying filters")
-	DebugLogf("Current filters: %+v", s.Filters) // %+v prints a struct with field names
-	// set the shown image to the unmodified image
-	s.WorkingImage.Pix = append([]uint8(nil), s.OrigImage.Pix...) // NOTE: found another copy by reference bug her
-	if !bytes.Equal(s.WorkingImage.Pix, s.OrigImage.Pix) {
-		FatalLog("Pixels copied incorrectly")
-	}
-
-	for _, k := range s.Filters.Order {
+// DrawPreview draws the latest render where the image goes, shrunk to fit if a stage made it bigger than the preview
+func (s *State) DrawPreview() {
+	width, height := float32(s.CurrentTexture.Width), float32(s.CurrentTexture.Height)
+	scale := min(1, float32(s.ShownImage.Width)/width, float32(s.ShownImage.Height)/height)
+	rl.DrawTextureEx(s.CurrentTexture, rl.NewVector2(0, 0), 0, scale, rl.White)
+}
 
-		// for each filter, apply it to the shown image
-		if s.Filters.IsGrayscaleEnabled && k == "control.grayscale" {
-			t := time.Now()
-			s.GrayscaleFilter()
-			InfoLogf("Grayscale filter time: %v", time.Since(t))
-		}
-		if s.Filters.ChannelAdjustmentEnabled && k == "control.channeladjustment" {
-			t := time.Now()
-			s.TintFilter()
-			InfoLogf("Tint filter time: %v", time.Since(t))
-		}
-		// SLOWish
-		if s.Filters.IsQuantizingEnabled && k == "control.quantizing" {
-			t := time.Now()
-			s.QuantizingFilter()
-			InfoLogf("Quant. filter time: %v", time.Since(t))
-		}
-		// SLOW
-		if s.Filters.IsDitheringEnabled && k == "control.dithering" {
-			t := time.Now()
-			s.DitheringFilter()
-			InfoLogf("Dither filter time: %v", time.Since(t))
-		}
-		if s.Filters.IsBoxBlurEnabled && k == "control.boxblur" {
-			t := time.Now()
-			s.BoxBlurFilter()
-			InfoLogf("Gaussian filter time: %v", time.Since(t))
-		}
-		if k == "control.lightendarken" {
-			t := time.Now()
-			s.LightenDarken()
-			InfoLogf("Lighten/darken filter time: %v", time.Since(t))
-		}
+// RenderFullResolution applies the filters to a copy of the full resolution original, this is slow so it's only used for exporting
+func (s *State) RenderFullResolution() *image.RGBA {
+	InfoLogf("Rendering at full resolution %dx%d", s.OrigImage.Rect.Dx(), s.OrigImage.Rect.Dy())
+	img := ToRGBA(&s.OrigImage)
+	// sizes in pixels were picked while looking at the preview so they're scaled up to match the original
+	filters := s.Filters.Clone()
+	if w := s.PreviewImage.Rect.Dx(); w > 0 {
+		filters.Params[PixelScaleKey] = float64(s.OrigImage.Rect.Dx()) / float64(w)
 	}
-	s.ShownImage = rl.NewImageFromImage(&s.WorkingImage)
+	filters.Apply(img)
+	return img
 }
 
 // TODO: logging not terminating colour escape codes
@@ -368,24 +339,19 @@ func (s *State) LoadLanguageData() {
 func (s *State) Init() {
 	// Image loading will be called from main when file is drag&dropped
 	InfoLog("Initialising state")
-	s.LoadFonts()
 	InfoLog("Initialising filters")
-	s.Filters = Filters{
-		DitheringQuantizationBuckets: 190,
-		QuantizingBands:              50,
-		ChannelAdjustment:            [3]float32{1.0, 1.0, 1.0},
-		BoxBlurIterations:            3,
-		LightenDarken:                0.0,
-		Order:                        [FilterCount]string{"control.grayscale", "control.quantizing", "control.dithering", "control.channeladjustment", "control.boxblur", "control.lightendarken"}, // initial Order
-	}
+	s.Filters = NewFilters()
+	s.History.Record(s.Filters)
 	InfoLog("Initialising windows")
 	s.FilterWindow = FilterOrderWindow{
 		Showing: false,
 		Anchor:  rl.Vector2{X: 20, Y: 20},
 	}
 	s.PaletteWindow = PaletteWindow{
-		Showing: false,
-		Anchor:  rl.Vector2{X: 20, Y: 20},
+		Showing:         false,
+		Anchor:          rl.Vector2{X: 20, Y: 20},
+		ExtractionCount: 8,
+		FilePath:        "palette.gpl",
 	}
 	s.HelpWindow = HelpWindow{
 		Showing: false,
@@ -395,47 +361,145 @@ func (s *State) Init() {
 		Showing:                  false,
 		Anchor:                   rl.Vector2{X: 20, Y: 20},
 		IsFileTypeDropDownActive: false,
+		ProjectPath:              "project" + ProjectExtension,
 	}
 	s.SettingsWindow = SettingsWindow{
 		Showing: false,
 		Anchor:  rl.Vector2{X: 20, Y: 20},
 	}
+	s.HistoryWindow = HistoryWindow{
+		Showing: false,
+		Anchor:  rl.Vector2{X: 20, Y: 20},
+	}
+	s.PresetsWindow = PresetsWindow{
+		Showing:  false,
+		Anchor:   rl.Vector2{X: 20, Y: 20},
+		FilePath: "presets.json",
+	}
+	s.ConvolutionWindow = ConvolutionWindow{
+		Showing: false,
+		Anchor:  rl.Vector2{X: 20, Y: 20},
+	}
+	s.CurvesWindow = CurvesWindow{
+		Showing:  false,
+		Anchor:   rl.Vector2{X: 20, Y: 20},
+		Dragging: -1,
+	}
 
 	InfoLog("Initialising language data")
 	s.LoadLanguageData()
 
-	s.Config.FileFormat = TIFF
-	InfoLog("Initialising font size")
-	s.Config.FontSize = 10
-	s.SetFontSize()
+	InfoLog("Initialising settings")
+	s.LoadSettings()
+	InfoLog("Initialising presets")
+	s.LoadPresets()
 	InfoLog("Finished state init")
 
 }
 
+// LoadSettings reads the settings file and applies the theme, font and font size from it
+func (s *State) LoadSettings() {
+	s.Config = NewConfig()
+	path, err := ConfigPath()
+	if err == nil {
+		InfoLogf("Loading settings from %v", path)
+		s.Config, err = LoadConfig(path)
+	}
+	if err != nil {
+		ErrorLogf("Couldn't load settings, using the defaults: %v", err.Error())
+	}
+	s.SavedConfig = s.Config
+	// changing the theme loads the fonts too
+	s.ChangeTheme()
+	s.SetFontSize()
+}
+
+// SaveSettingsIfChanged writes the settings file if the config has changed since it was last saved
+func (s *State) SaveSettingsIfChanged() {
+	if s.Config == s.SavedConfig {
+		return
+	}
+	// remember it even if saving fails so it doesn't retry every frame
+	s.SavedConfig = s.Config
+	path, err := ConfigPath()
+	if err == nil {
+		InfoLogf("Saving settings to %v", path)
+		err = SaveConfig(path, s.Config)
+	}
+	if err != nil {
+		ErrorLogf("Couldn't save settings: %v", err.Error())
+	}
+}
+
+// LoadPresets reads the user's presets, falling back to just the built in ones
+func (s *State) LoadPresets() {
+	s.Presets = NewPresetLibrary()
+	path, err := PresetsPath()
+	if err == nil {
+		s.Presets, err = LoadPresetLibrary(path)
+	}
+	if err != nil {
+		ErrorLogf("Couldn't load presets: %v", err.Error())
+	}
+}
+
+// SavePresets writes the user's presets so they're there next launch
+func (s *State) SavePresets() {
+	path, err := PresetsPath()
+	if err == nil {
+		err = SavePresetLibrary(path, &s.Presets)
+	}
+	if err != nil {
+		ErrorLogf("Couldn't save presets: %v", err.Error())
+	}
+}
+
+// ApplyPreset replaces the current filters with the named preset's
+func (s *State) ApplyPreset(name string) error {
+	preset, ok := s.Presets.Get(name)
+	if !ok {
+		return fmt.Errorf("there's no preset called %q", name)
+	}
+	s.Filters = preset.Filters.WithDefaults()
+	s.History.Record(s.Filters)
+	return nil
+}
+
 func (s *State) SaveImage() {
-	extension := s.Config.GetActiveFileFormat().String()
-	InfoLogf("Saving as output.%s", extension)
+	format := s.Config.GetActiveFileFormat()
+	InfoLogf("Saving as output.%s", format.String())
 	// create a file with the current format's extension
-	f, err := os.Create("./output." + extension)
+	f, err := os.Create("./output." + format.String())
 	if err != nil {
 		FatalLogf("Couldn't create file: %v", err.Error())
 	}
-	// write the image to the file
-	switch s.Config.FileFormat {
+	defer f.Close()
+	// write the image to the file, the working image is only preview sized so render the original
+	err = EncodeImage(f, s.RenderFullResolution(), format)
+	if err != nil {
+		FatalLogf("Couldn't encode image: %v", err.Error())
+	}
+}
+
+// EncodeImage writes img to w in the given file format
+func EncodeImage(w io.Writer, img image.Image, format FileFormat) error {
+	switch format {
 	case PNG:
-		err = png.Encode(f, image.Image(&s.WorkingImage))
+		return png.Encode(w, img)
 	case TIFF:
-		err = tiff.Encode(f, image.Image(&s.WorkingImage), &tiff.Options{Compression: tiff.Uncompressed, Predictor: true})
+		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Uncompressed, Predictor: true})
 	case BMP:
-		err = bmp.Encode(f, image.Image(&s.WorkingImage))
+		return bmp.Encode(w, img)
 	case JPG:
-		err = jpeg.Encode(f, image.Image(&s.WorkingImage), &jpeg.Options{Quality: 100})
-	}
-	if err != nil {
-		FatalLogf("Couldn't encode image: %v", err.Error())
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 100})
 	}
+	return fmt.Errorf("unsupported file format %d", format)
 }
 
+// IsEditingText is true while any text box is being typed in, so typing doesn't trigger hotkeys
+func (s *State) IsEditingText() bool {
+	return s.SaveLoadWindow.IsProjectPathEditing || s.PresetsWindow.IsNameEditing || s.PresetsWindow.IsFilePathEditing || s.PaletteWindow.IsFilePathEditing || s.ConvolutionWindow.Editing != ""
+}
 
 // Close the application
 func (s *State) Close() {
@@ -14,7 +14,7 @@ func TestGrayscale(t *testing.T) {
 				Pix: []uint8{255, 0, 0, 255, 0, 99, 0, 255}, // Red, Green
 			},
 		}
-		s.GrayscaleFilter()
+		GrayscaleFilter{}.Apply(&s.WorkingImage, nil)
 		if s.WorkingImage.Pix[0] != 85 || s.WorkingImage.Pix[1] != 85 || s.WorkingImage.Pix[2] != 85 {
 			t.Error("Grayscale filter failed, expected 85, 85, 85, got", s.WorkingImage.Pix[0], s.WorkingImage.Pix[1], s.WorkingImage.Pix[2])
 		}
@@ -29,13 +29,82 @@ func TestGrayscale(t *testing.T) {
 				Pix: []uint8{255, 0, 0, 34, 0, 255, 0, 75}, // Red, Green, Blue pixels
 			},
 		}
-		s.GrayscaleFilter()
+		GrayscaleFilter{}.Apply(&s.WorkingImage, nil)
 		if s.WorkingImage.Pix[3] != 34 || s.WorkingImage.Pix[7] != 75 {
 			t.Error("Grayscale filter mutated alpha values: ", s.WorkingImage.Pix)
 		}
 	})
 }
 
+func TestRenderFullResolution(t *testing.T) {
+	// Aim: exports should be rendered from the full resolution original, not the preview
+	s := State{
+		OrigImage:    *image.NewRGBA(image.Rect(0, 0, 300, 200)),
+		PreviewImage: *image.NewRGBA(image.Rect(0, 0, 30, 20)),
+		Filters:      NewFilters(),
+	}
+	s.Filters.Enabled["control.grayscale"] = true
+	s.OrigImage.Pix[0] = 255
+	res := s.RenderFullResolution()
+	if res.Bounds() != s.OrigImage.Bounds() {
+		t.Errorf("Expected bounds %v, got %v", s.OrigImage.Bounds(), res.Bounds())
+	}
+	if res.Pix[0] != 85 || res.Pix[1] != 85 || res.Pix[2] != 85 {
+		t.Error("Filters weren't applied, got", res.Pix[:4])
+	}
+	if s.OrigImage.Pix[0] != 255 {
+		t.Error("Rendering modified the original image")
+	}
+}
+
+func TestRenderFullResolutionPixelScale(t *testing.T) {
+	// Aim: a blur set up on a preview a tenth of the size should spread ten times as far in the export
+	s := State{
+		OrigImage:    *image.NewRGBA(image.Rect(0, 0, 300, 200)),
+		PreviewImage: *image.NewRGBA(image.Rect(0, 0, 30, 20)),
+		Filters:      NewFilters(),
+	}
+	// a white line down the middle
+	for y := range 200 {
+		i := y*s.OrigImage.Stride + 150*4
+		s.OrigImage.Pix[i+0], s.OrigImage.Pix[i+1], s.OrigImage.Pix[i+2], s.OrigImage.Pix[i+3] = 255, 255, 255, 255
+	}
+	s.Filters.Enabled["control.gaussianblur"] = true
+	s.Filters.Params["control.gaussianblur.radius"] = 2
+	s.Filters.Params["control.gaussianblur.sigma"] = 1
+	res := s.RenderFullResolution()
+	if v := res.Pix[100*res.Stride+160*4]; v == 0 {
+		t.Error("Expected the blur to reach 10 pixels from the line")
+	}
+	if s.Filters.Params[PixelScaleKey] != 1 {
+		t.Error("Rendering changed the filters' pixel scale")
+	}
+}
+
+func TestPreviewFiltersSourceScale(t *testing.T) {
+	// Aim: resizing to 800x600 should give exactly that in the export and the same shape shrunk to the preview
+	s := State{
+		OrigImage:    *image.NewRGBA(image.Rect(0, 0, 1000, 500)),
+		PreviewImage: *image.NewRGBA(image.Rect(0, 0, 100, 50)),
+		Filters:      NewFilters(),
+	}
+	s.Filters.Enabled["control.resize"] = true
+	s.Filters.Params["control.resize.mode"] = float64(ResizeSize)
+	s.Filters.Params["control.resize.targetwidth"] = 800
+	s.Filters.Params["control.resize.targetheight"] = 600
+	if res := s.RenderFullResolution(); res.Rect.Dx() != 800 || res.Rect.Dy() != 600 {
+		t.Errorf("Expected an 800x600 export, got %v", res.Rect)
+	}
+	preview := ToRGBA(&s.PreviewImage)
+	s.PreviewFilters().Apply(preview)
+	if preview.Rect.Dx() != 80 || preview.Rect.Dy() != 60 {
+		t.Errorf("Expected an 80x60 preview, got %v", preview.Rect)
+	}
+	if s.Filters.Params[SourceScaleKey] != 1 {
+		t.Error("Previewing changed the filters' source scale")
+	}
+}
+
 //func TestQuantization(t *testing.T) {
 //	t.Run("Simple Quantization", func(t *testing.T) {
 //		s := State{
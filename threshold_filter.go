@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"image"
+	"slices"
+)
+
+// ThresholdMode picks how the threshold each pixel is compared with is found
+type ThresholdMode int
+
+const (
+	ThresholdManual        ThresholdMode = iota // the same level everywhere
+	ThresholdOtsu                               // the level that best splits the histogram into two groups
+	ThresholdAdaptiveMean                       // the mean of the pixels around each pixel
+	ThresholdAdaptiveGauss                      // the gaussian weighted mean of the pixels around each pixel
+)
+
+// Turns the image into pure black and white by comparing the luma of every pixel with a threshold
+type ThresholdFilter struct{}
+
+func (ThresholdFilter) Name() string {
+	return "control.threshold"
+}
+func (ThresholdFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.threshold.mode", Kind: ParamChoice, Min: 0, Max: float64(ThresholdAdaptiveGauss), Default: float64(ThresholdManual), Options: []string{
+			"control.threshold.manual",
+			"control.threshold.otsu",
+			"control.threshold.mean",
+			"control.threshold.gaussian",
+		}},
+		{Key: "control.threshold.level", Kind: ParamInt, Min: 0, Max: 255, Default: 127},
+		{Key: "control.threshold.radius", Kind: ParamInt, Min: 1, Max: 50, Default: 7},
+		{Key: "control.threshold.offset", Kind: ParamInt, Min: -50, Max: 50, Default: 5},
+		pixelScaleParam,
+	}
+}
+func (f ThresholdFilter) Apply(img *image.RGBA, p Params) {
+	f.ApplyContext(context.Background(), img, p)
+}
+func (ThresholdFilter) ApplyContext(ctx context.Context, img *image.RGBA, p Params) error {
+	mode := ThresholdMode(p.Int("control.threshold.mode"))
+	// the image as grey, the pixels are compared with the same pixels of the thresholds
+	grey := ToRGBA(img)
+	ParallelPix(grey, func(pix []uint8) {
+		for i := 0; i < len(pix); i += 4 {
+			v := Grey(GrayscaleRec601, pix[i+0], pix[i+1], pix[i+2], false)
+			pix[i+0], pix[i+1], pix[i+2], pix[i+3] = v, v, v, 255
+		}
+	})
+	var level func(i int) int
+	switch mode {
+	case ThresholdAdaptiveMean, ThresholdAdaptiveGauss:
+		radius := scalePixels(p, p.Int("control.threshold.radius"))
+		kernel := GaussianKernel(radius, float64(radius)/2)
+		if mode == ThresholdAdaptiveMean {
+			kernel = slices.Repeat([]float32{1 / float32(2*radius+1)}, 2*radius+1)
+		}
+		local := ToRGBA(grey)
+		if err := ConvolveSeparable(ctx, local, kernel, kernel, EdgeMirror); err != nil {
+			return err
+		}
+		// pixels only go black when they're offset darker than what's around them, so flat areas like paper
+		// stay white instead of turning into noise
+		offset := p.Int("control.threshold.offset")
+		level = func(i int) int { return int(local.Pix[i]) - offset }
+	case ThresholdOtsu:
+		var hist [256]int
+		for i := 0; i < len(grey.Pix); i += 4 {
+			hist[grey.Pix[i]]++
+		}
+		t := OtsuThreshold(hist)
+		level = func(int) int { return t }
+	default:
+		t := p.Int("control.threshold.level")
+		level = func(int) int { return t }
+	}
+	bounds := img.Bounds()
+	w := bounds.Dx()
+	return ParallelRowsContext(ctx, bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := range w {
+				i, g := y*img.Stride+x*4, y*grey.Stride+x*4
+				// anything brighter than the threshold is white
+				v := uint8(0)
+				if int(grey.Pix[g]) > level(g) {
+					v = 255
+				}
+				img.Pix[i+0], img.Pix[i+1], img.Pix[i+2] = v, v, v
+			}
+		}
+	})
+}
+
+// OtsuThreshold finds the level that splits hist into a dark group (at or below it) and a light group (above it)
+// with as much variance between the two groups as possible. A histogram with one value or none gives 127
+func OtsuThreshold(hist [256]int) int {
+	var total, sum float64
+	for v, count := range hist {
+		total += float64(count)
+		sum += float64(v * count)
+	}
+	best, bestVariance := 127, 0.0
+	var darkCount, darkSum float64
+	for t, count := range hist {
+		darkCount += float64(count)
+		darkSum += float64(t * count)
+		lightCount := total - darkCount
+		if darkCount == 0 || lightCount == 0 {
+			continue
+		}
+		darkMean, lightMean := darkSum/darkCount, (sum-darkSum)/lightCount
+		if variance := darkCount * lightCount * (darkMean - lightMean) * (darkMean - lightMean); variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return best
+}
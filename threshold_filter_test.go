@@ -0,0 +1,69 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestThresholdFilter(t *testing.T) {
+	t.Run("Output is 1-bit", func(t *testing.T) {
+		// Aim: every mode leaves only pure black and white, with alpha left alone
+		for mode := ThresholdManual; mode <= ThresholdAdaptiveGauss; mode++ {
+			img := randomImage(32, 32)
+			expected := ToRGBA(img)
+			p := NewFilters().Params
+			p["control.threshold.mode"] = float64(mode)
+			ThresholdFilter{}.Apply(img, p)
+			for i := 0; i < len(img.Pix); i += 4 {
+				r, g, b := img.Pix[i+0], img.Pix[i+1], img.Pix[i+2]
+				if r != g || g != b || r != 0 && r != 255 {
+					t.Fatalf("Expected mode %d to give black or white, got %d, %d, %d", mode, r, g, b)
+				}
+				if img.Pix[i+3] != expected.Pix[i+3] {
+					t.Fatalf("Expected mode %d to leave alpha alone", mode)
+				}
+			}
+		}
+	})
+	t.Run("Otsu splits two groups", func(t *testing.T) {
+		// Aim: with a dark group and a light group the threshold should fall between them
+		var hist [256]int
+		for v := 30; v <= 50; v++ {
+			hist[v] = 10
+		}
+		for v := 180; v <= 220; v++ {
+			hist[v] = 5
+		}
+		if level := OtsuThreshold(hist); level < 50 || level >= 180 {
+			t.Errorf("Expected the threshold between 50 and 180, got %d", level)
+		}
+	})
+	t.Run("Adaptive handles uneven lighting", func(t *testing.T) {
+		// Aim: dark text on paper that fades from light to dark should still come out as black text on white
+		img := image.NewRGBA(image.Rect(0, 0, 64, 16))
+		for y := range 16 {
+			for x := range 64 {
+				paper := 250 - x*3
+				v := paper
+				// a dark line down every 8th column
+				if x%8 == 4 {
+					v = paper - 60
+				}
+				i := y*img.Stride + x*4
+				img.Pix[i+0], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(v), uint8(v), uint8(v), 255
+			}
+		}
+		p := NewFilters().Params
+		p["control.threshold.mode"] = float64(ThresholdAdaptiveMean)
+		ThresholdFilter{}.Apply(img, p)
+		for x := range 64 {
+			expected := uint8(255)
+			if x%8 == 4 {
+				expected = 0
+			}
+			if v := img.Pix[8*img.Stride+x*4]; v != expected {
+				t.Errorf("Expected column %d to be %d, got %d", x, expected, v)
+			}
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import "image"
+
+type TintFilter struct{}
+
+func (TintFilter) Name() string {
+	return "control.channeladjustment"
+}
+func (TintFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.channeladjustment.red", Kind: ParamFloat, Min: 0, Max: 1, Default: 1},
+		{Key: "control.channeladjustment.green", Kind: ParamFloat, Min: 0, Max: 1, Default: 1},
+		{Key: "control.channeladjustment.blue", Kind: ParamFloat, Min: 0, Max: 1, Default: 1},
+	}
+}
+func (TintFilter) Apply(img *image.RGBA, p Params) {
+	DebugLog("Tint filter applied")
+	r := float32(p.Float("control.channeladjustment.red"))
+	g := float32(p.Float("control.channeladjustment.green"))
+	b := float32(p.Float("control.channeladjustment.blue"))
+	ParallelPix(img, func(pix []uint8) {
+		for i := 0; i < len(pix); i += 4 {
+			pix[i+0] = uint8(float32(pix[i+0]) * r)
+			pix[i+1] = uint8(float32(pix[i+1]) * g)
+			pix[i+2] = uint8(float32(pix[i+2]) * b)
+		}
+	})
+}
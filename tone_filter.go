@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Exposure, contrast, gamma and a highlight roll-off, all worked out once into a lookup table
+type ToneFilter struct{}
+
+func (ToneFilter) Name() string {
+	return "control.tone"
+}
+func (ToneFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Key: "control.tone.exposure", Kind: ParamFloat, Min: -5, Max: 5, Default: 0},
+		{Key: "control.tone.contrast", Kind: ParamFloat, Min: -1, Max: 1, Default: 0},
+		{Key: "control.tone.pivot", Kind: ParamFloat, Min: 0, Max: 1, Default: 0.5},
+		{Key: "control.tone.gamma", Kind: ParamFloat, Min: 0.1, Max: 5, Default: 1},
+		{Key: "control.tone.rolloff", Kind: ParamFloat, Min: 0, Max: 1, Default: 0},
+	}
+}
+func (ToneFilter) Apply(img *image.RGBA, p Params) {
+	lut := ToneLUT(
+		p.Float("control.tone.exposure"),
+		p.Float("control.tone.contrast"),
+		p.Float("control.tone.pivot"),
+		p.Float("control.tone.gamma"),
+		p.Float("control.tone.rolloff"),
+	)
+	ApplyLUTs(img, lut, lut, lut)
+}
+
+// ToneLUT works out the tone curve for every channel value:
+//   - exposure is in stops, each one doubles the light, so it's applied in linear light
+//   - rolloff is how much of the top of the range is used to ease highlights into white instead of clipping them,
+//     0 clips and 1 compresses everything
+//   - contrast scales values away from pivot, 1 makes the slope 4 times steeper and -1 makes it 4 times shallower
+//   - gamma bends the result, above 1 brightens the mid tones like in levels
+func ToneLUT(exposure, contrast, pivot, gamma, rolloff float64) LUT {
+	scale := math.Exp2(exposure)
+	slope := math.Exp2(2 * contrast)
+	// values in linear light above the knee get compressed
+	knee := 1 - rolloff
+	var l LUT
+	for i := range l {
+		v := ToLinear(uint8(i)) * scale
+		if rolloff > 0 && v > knee {
+			// an exponential shoulder that leaves the knee with the same slope and only reaches white at infinity
+			v = knee + rolloff*(1-math.Exp(-(v-knee)/rolloff))
+		}
+		v = EncodeSRGB(Clamp(v, 0, 1))
+		v = Clamp(pivot+(v-pivot)*slope, 0, 1)
+		v = math.Pow(v, 1/gamma)
+		l[i] = uint8(math.Round(v * 255))
+	}
+	return l
+}
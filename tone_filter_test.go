@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestToneFilter(t *testing.T) {
+	t.Run("Default tone leaves the image alone", func(t *testing.T) {
+		img := randomImage(16, 16)
+		expected := ToRGBA(img)
+		ToneFilter{}.Apply(img, NewFilters().Params)
+		if !bytes.Equal(img.Pix, expected.Pix) {
+			t.Error("Default tone changed the image")
+		}
+	})
+	t.Run("Exposure is in stops", func(t *testing.T) {
+		// Aim: one stop up doubles the light and one stop down halves it
+		up, down := ToneLUT(1, 0, 0.5, 1, 0), ToneLUT(-1, 0, 0.5, 1, 0)
+		for _, v := range []uint8{20, 64, 100} {
+			if got := ToLinear(up[v]); got < ToLinear(v)*2*0.97 || got > ToLinear(v)*2*1.03 {
+				t.Errorf("Expected +1 stop to double the light of %d, got %d", v, up[v])
+			}
+			if got := ToLinear(down[v]); got < ToLinear(v)/2*0.95 || got > ToLinear(v)/2*1.05 {
+				t.Errorf("Expected -1 stop to halve the light of %d, got %d", v, down[v])
+			}
+		}
+	})
+	t.Run("Contrast turns around the pivot", func(t *testing.T) {
+		// Aim: the pivot stays put while values either side move away from it, or towards it with negative contrast
+		more, less := ToneLUT(0, 0.5, 100.0/255, 1, 0), ToneLUT(0, -0.5, 100.0/255, 1, 0)
+		if more[100] != 100 || less[100] != 100 {
+			t.Errorf("Expected the pivot to stay at 100, got %d and %d", more[100], less[100])
+		}
+		if more[80] >= 80 || more[120] <= 120 {
+			t.Errorf("Expected more contrast to spread 80 and 120 apart, got %d and %d", more[80], more[120])
+		}
+		if less[80] <= 80 || less[120] >= 120 {
+			t.Errorf("Expected less contrast to pull 80 and 120 together, got %d and %d", less[80], less[120])
+		}
+	})
+	t.Run("Gamma bends the mid tones", func(t *testing.T) {
+		// Aim: the same convention as levels, above 1 brightens
+		l := ToneLUT(0, 0, 0.5, 2, 0)
+		if l[128] <= 128 || l[0] != 0 || l[255] != 255 {
+			t.Errorf("Expected gamma 2 to brighten 128 and keep black and white, got %d, %d and %d", l[128], l[0], l[255])
+		}
+	})
+	t.Run("Highlight roll-off", func(t *testing.T) {
+		// Aim: without roll-off over exposed highlights clip, with it they stay apart and still get brighter
+		clipped, rolled := ToneLUT(1, 0, 0.5, 1, 0), ToneLUT(1, 0, 0.5, 1, 0.5)
+		if clipped[200] != 255 || clipped[230] != 255 {
+			t.Errorf("Expected 1 stop to clip 200 and 230, got %d and %d", clipped[200], clipped[230])
+		}
+		for v := 1; v < 256; v++ {
+			if rolled[v] < rolled[v-1] {
+				t.Fatalf("Expected the roll-off to never get darker, %d went to %d after %d", v, rolled[v], rolled[v-1])
+			}
+		}
+		if rolled[200] >= rolled[230] || rolled[230] >= rolled[255] {
+			t.Errorf("Expected the roll-off to keep highlights apart, got %d, %d and %d", rolled[200], rolled[230], rolled[255])
+		}
+	})
+}
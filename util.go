@@ -3,8 +3,7 @@ package main
 import (
 	"fmt"
 	"image"
-	"image/color"
-	"math"
+	"image/draw"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -18,23 +17,6 @@ func ClampByte(value int) int {
 	}
 	return value
 }
-func distributeError(img *image.RGBA, x, y int, errR, errG, errB uint8, factor float64) {
-	bounds := img.Bounds()
-	xBoundInImage := x >= bounds.Min.X && x < bounds.Max.X
-	yBoundInImage := y >= bounds.Min.Y && y < bounds.Max.Y
-	if xBoundInImage && yBoundInImage {
-		currR, currG, currB, _ := img.At(x, y).RGBA()
-
-		currR /= 257
-		currG /= 257
-		currB /= 257
-
-		newR := ClampByte(int(currR) + int(float64(errR)*factor))
-		newG := ClampByte(int(currG) + int(float64(errG)*factor))
-		newB := ClampByte(int(currB) + int(float64(errB)*factor))
-		img.SetRGBA(x, y, color.RGBA{R: uint8(newR), G: uint8(newG), B: uint8(newB), A: 255})
-	}
-}
 
 func removeDuplicates[T comparable](s []T) []T {
 	seen := make(map[T]bool)
@@ -77,16 +59,22 @@ func PixSliceToColourSlice(pix []uint8) []rl.Color {
 	return res
 }
 
-// QuantizeValue quantizes a value into a bucketCount number of buckets
-// exceptions: bandCount = 0 -> return 0
-// bandCount = 1 -> return value
+// BucketSize is the gap between the levels Quantize rounds down to, 0 when there are more bands than values
+func BucketSize(bandCount uint8) uint8 {
+	// worked out as an int so 255 bands doesn't wrap round to dividing by 0
+	return uint8(255 / (int(bandCount) + 1))
+}
+
+// Quantize rounds a value down to a multiple of the bucket size for bandCount buckets, so the top level can stop
+// short of white. Error diffusion dithering and the quantizing filter have always used these levels, posterize is
+// the one that spreads its levels out to white
+// exceptions: bandCount = 0 or 1, or too many bands for the buckets to be any size -> return value
 func Quantize(bandCount, v uint8) uint8 {
-	if bandCount == 1 || bandCount == 0 {
+	bucketSize := BucketSize(bandCount)
+	if bandCount <= 1 || bucketSize <= 1 {
 		return v
 	}
-	// quantize a function into bandCount buckets
-	bucketSize := uint8(math.Trunc(float64(255) / float64(bandCount+1)))
-	return uint8(math.Trunc(float64(v)/float64(bucketSize)) * float64(bucketSize))
+	return v / bucketSize * bucketSize
 }
 
 func Translate(in string) string {
@@ -123,3 +111,11 @@ func MapOut[T any](vals []T, f func(T) T) []T {
 	}
 	return res
 }
+
+// ToRGBA copies any image into a new RGBA image with its origin at 0, 0
+func ToRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	res := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(res, res.Bounds(), img, bounds.Min, draw.Src)
+	return res
+}
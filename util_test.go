@@ -89,13 +89,10 @@ func TestRemoveDuplicates(t *testing.T) {
 }
 func TestQuantizeValue(t *testing.T) {
 	t.Run("Test zero bands", func(t *testing.T) {
-		res := make([]uint8, 256)
-		for i := 0; i < 256; i++ {
-			res[i] = Quantize(0, uint8(i))
-		}
-		RHS := make([]uint8, 256)
-		if !slices.Equal(res, RHS) {
-			t.Errorf("Expected %v, got %v", RHS, res)
+		for i := range 255 {
+			if Quantize(0, uint8(i)) != uint8(i) {
+				t.Errorf("Expected %d got %d", i, Quantize(0, uint8(i)))
+			}
 		}
 	})
 	t.Run("Test 255 bands", func(t *testing.T) {
@@ -113,14 +110,23 @@ func TestQuantizeValue(t *testing.T) {
 			}
 		}
 	})
+	t.Run("Levels are multiples of the bucket size", func(t *testing.T) {
+		// Aim: with 8 buckets of 28 the top level is 252, dithering has always stopped short of white there
+		if res := Quantize(8, 255); res != 252 {
+			t.Errorf("Expected 252 got %d", res)
+		}
+		if res := Quantize(8, 251); res != 224 {
+			t.Errorf("Expected 224 got %d", res)
+		}
+	})
 	t.Run("Test normal case", func(t *testing.T) {
 		bandCount := uint8(4)
 		res := make([]uint8, 256)
 		for i := 0; i < 256; i++ {
 			res[i] = Quantize(bandCount, uint8(i))
 		}
-		removeDuplicates(res)
-		expected := []uint8{0}
+		res = removeDuplicates(res)
+		expected := []uint8{0, 51, 102, 153, 204, 255}
 		if !slices.Equal(res, expected) {
 			t.Errorf("Expected %v, got %v", expected, res)
 		}